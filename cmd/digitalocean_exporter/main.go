@@ -2,13 +2,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Sirupsen/logrus"
@@ -30,9 +34,71 @@ var (
 	metricsPath     = flag.String("metrics-path", "/metrics", "URL path for surfacing metrics")
 	apiToken        = flag.String("token", "", "DigitalOcean API token (read-only)")
 	refreshInterval = flag.Int("refresh-interval", digitaloceanexporter.DefaultRefreshInterval, "Interval (in seconds) between subsequent requests against DigitalOcean API")
+	maxStaleness    = flag.Int("max-staleness", 0, "Age (in seconds) after which data retained from the last successful refresh is no longer served (0 serves it indefinitely)")
+	concurrency     = flag.Int("concurrency", digitaloceanexporter.DefaultConcurrency, "Maximum number of concurrent requests against DigitalOcean API")
+	refreshTimeout  = flag.Int("refresh-timeout", 0, "Deadline (in seconds) for a whole refresh against DigitalOcean API (0 means no deadline)")
+	requestTimeout  = flag.Int("request-timeout", 30, "Deadline (in seconds) for a single request against DigitalOcean API (0 means no deadline)")
+	maxRetries      = flag.Int("max-retries", digitaloceanexporter.DefaultMaxRetries, "Number of times a request against DigitalOcean API which failed with a transient error is retried")
+	adaptiveRefresh = flag.Bool("adaptive-refresh", false, "Stretch the refresh interval to keep requests against DigitalOcean API within the rate limit budget")
+	rateLimitBudget = flag.Float64("rate-limit-budget", digitaloceanexporter.DefaultRateLimitBudget, "Fraction of the hourly DigitalOcean API rate limit that refreshes may use with -adaptive-refresh")
+	tagsInclude     = flag.String("droplet-tags.include", "", "Regular expression of the Droplet tags kept in the tags label (empty keeps all tags)")
+	tagsPromote     = flag.String("droplet-tags.promote", "", "Comma-separated list of Droplet tags exported as a tag_<name> label of their own")
 	versionFlag     = flag.Bool("v", false, "Prints current digitalocean_exporter version")
 )
 
+// resourceRefreshIntervals holds the per-resource refresh interval flags,
+// keyed by resource type.
+var resourceRefreshIntervals = make(map[string]*int)
+
+// collectorFlags and noCollectorFlags hold the flags enabling and disabling
+// each collector, keyed by collector name.
+var (
+	collectorFlags   = make(map[string]*bool)
+	noCollectorFlags = make(map[string]*bool)
+)
+
+func init() {
+	defaults := digitaloceanexporter.DefaultResourceRefreshIntervals()
+	for _, resource := range digitaloceanexporter.Resources() {
+		usage := fmt.Sprintf("Interval (in seconds) between subsequent refreshes of %s", resource)
+		if _, ok := defaults[resource]; !ok {
+			usage += " (0 uses -refresh-interval)"
+		}
+
+		resourceRefreshIntervals[resource] = flag.Int(
+			"refresh-interval."+resource,
+			defaults[resource],
+			usage,
+		)
+	}
+
+	for name, enabled := range digitaloceanexporter.Collectors() {
+		collectorFlags[name] = flag.Bool(
+			"collector."+name,
+			enabled,
+			fmt.Sprintf("Enable the %s collector", name),
+		)
+		noCollectorFlags[name] = flag.Bool(
+			"no-collector."+name,
+			false,
+			fmt.Sprintf("Disable the %s collector", name),
+		)
+	}
+}
+
+// enabledCollectors returns the names of the collectors enabled by the
+// -collector.<name> and -no-collector.<name> flags.
+func enabledCollectors() []string {
+	var collectors []string
+	for name, enabled := range collectorFlags {
+		if *enabled && !*noCollectorFlags[name] {
+			collectors = append(collectors, name)
+		}
+	}
+	sort.Strings(collectors)
+	return collectors
+}
+
 // TokenSource holds an OAuth token.
 type TokenSource struct {
 	AccessToken string
@@ -122,13 +188,78 @@ func main() {
 	ua := []string{agent, version}
 	c.UserAgent = strings.Join(ua, "/")
 
-	digitalOceanBuffer := digitaloceanexporter.NewDigitalOceanBuffer(c, *refreshInterval)
+	intervals := make(map[string]time.Duration)
+	for resource, interval := range resourceRefreshIntervals {
+		if *interval > 0 {
+			intervals[resource] = time.Duration(*interval) * time.Second
+		}
+	}
+
+	var tags digitaloceanexporter.TagConfig
+	if *tagsInclude != "" {
+		include, err := regexp.Compile(*tagsInclude)
+		if err != nil {
+			logrus.Fatalf("Cannot parse -droplet-tags.include: %s", err)
+		}
+		tags.Include = include
+	}
+	for _, name := range strings.Split(*tagsPromote, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			tags.Promote = append(tags.Promote, name)
+		}
+	}
+	if err := tags.Validate(); err != nil {
+		logrus.Fatalf("Cannot use -droplet-tags.promote: %s", err)
+	}
+
+	collectors := enabledCollectors()
+	resources, err := digitaloceanexporter.CollectorResources(collectors)
+	if err != nil {
+		logrus.Fatalf("Cannot enable collectors: %s", err)
+	}
+	logrus.WithField("collectors", collectors).Infoln("Enabled collectors")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	digitalOceanBuffer := digitaloceanexporter.NewDigitalOceanBuffer(ctx, c, digitaloceanexporter.BufferConfig{
+		RefreshInterval: time.Duration(*refreshInterval) * time.Second,
+		MaxStaleness:    time.Duration(*maxStaleness) * time.Second,
+		Concurrency:     *concurrency,
+		RefreshTimeout:  time.Duration(*refreshTimeout) * time.Second,
+		RequestTimeout:  time.Duration(*requestTimeout) * time.Second,
+		MaxRetries:      *maxRetries,
+		AdaptiveRefresh: *adaptiveRefresh,
+		RateLimitBudget: *rateLimitBudget,
+
+		ResourceRefreshIntervals: intervals,
+		DropletTags:              tags,
+		Resources:                resources,
+	})
 	digitalOceanService := digitaloceanexporter.NewDigitalOceanService(digitalOceanBuffer)
-	newExporter := digitaloceanexporter.New(digitalOceanService)
+	newExporter, err := digitaloceanexporter.New(digitalOceanService, collectors)
+	if err != nil {
+		logrus.Fatalf("Cannot create DigitalOcean exporter: %s", err)
+	}
 	prometheus.MustRegister(newExporter)
 
+	server := &http.Server{
+		Addr:    *listenAddr,
+		Handler: newHandler(*metricsPath),
+	}
+
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-signals
+
+		logrus.WithField("signal", sig.String()).Infoln("Shutting down DigitalOcean exporter")
+		cancel()
+		server.Shutdown(context.Background())
+	}()
+
 	logrus.Printf("Starting DigitalOcean exporter on %q", *listenAddr)
-	if err := http.ListenAndServe(*listenAddr, newHandler(*metricsPath)); err != nil {
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		logrus.Fatalf("Cannot start DigitalOcean exporter: %s", err)
 	}
 }
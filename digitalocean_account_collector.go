@@ -0,0 +1,161 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	resources := []string{resourceAccount, resourceDroplets, resourceFloatingIPs, resourceVolumes}
+	registerCollector(resourceAccount, true, resources, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewAccountCollector(dos)
+	})
+}
+
+// An AccountCollector is a Prometheus collector for metrics regarding the
+// DigitalOcean account and how much of its limits are used.
+type AccountCollector struct {
+	Status        *prometheus.Desc
+	EmailVerified *prometheus.Desc
+	Limit         *prometheus.Desc
+	Usage         *prometheus.Desc
+	Utilization   *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that AccountCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &AccountCollector{}
+
+// NewAccountCollector creates a new AccountCollector which collects metrics
+// about a DigitalOcean account.
+func NewAccountCollector(dos DigitalOceanSource) *AccountCollector {
+	return &AccountCollector{
+		Status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "account", "status"),
+			"Status of the account, always 1.",
+			[]string{"status"},
+			nil,
+		),
+		EmailVerified: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "account", "email_verified"),
+			"Whether the email address of the account has been verified.",
+			nil,
+			nil,
+		),
+		Limit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "account", "limit"),
+			"Maximum number of resources the account may create by resource type.",
+			[]string{"resource"},
+			nil,
+		),
+		Usage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "account", "usage"),
+			"Number of resources counted against the account limits by resource type.",
+			[]string{"resource"},
+			nil,
+		),
+		Utilization: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "account", "limit_utilization_ratio"),
+			"Fraction of the account limit in use by resource type.",
+			[]string{"resource"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *AccountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Status,
+		c.EmailVerified,
+		c.Limit,
+		c.Usage,
+		c.Utilization,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to the account
+// to the provided prometheus Metric channel.
+func (c *AccountCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	account := s.Account()
+	if account == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Status,
+		prometheus.GaugeValue,
+		1,
+		account.Status,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.EmailVerified,
+		prometheus.GaugeValue,
+		boolToFloat64(account.EmailVerified),
+	)
+
+	floatingIPLimit := account.FloatingIPLimit
+	if floatingIPLimit == 0 {
+		floatingIPLimit = account.ReservedIPLimit
+	}
+
+	var droplets, floatingIPs, volumes int
+	for _, count := range s.Droplets() {
+		droplets += count
+	}
+	for _, count := range s.FloatingIPs() {
+		floatingIPs += count
+	}
+	for _, count := range s.Volumes() {
+		volumes += count
+	}
+
+	limits := []struct {
+		resource string
+		limit    int
+		usage    int
+	}{
+		{resourceDroplets, account.DropletLimit, droplets},
+		{resourceFloatingIPs, floatingIPLimit, floatingIPs},
+		{resourceVolumes, account.VolumeLimit, volumes},
+	}
+
+	for _, l := range limits {
+		ch <- prometheus.MustNewConstMetric(
+			c.Limit,
+			prometheus.GaugeValue,
+			float64(l.limit),
+			l.resource,
+		)
+
+		// Usage is unknown until the resource type has been refreshed.
+		if !s.available(l.resource) {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.Usage,
+			prometheus.GaugeValue,
+			float64(l.usage),
+			l.resource,
+		)
+
+		if l.limit > 0 {
+			ch <- prometheus.MustNewConstMetric(
+				c.Utilization,
+				prometheus.GaugeValue,
+				float64(l.usage)/float64(l.limit),
+				l.resource,
+			)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package digitaloceanexporter
+
+import (
+	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceApps, true, []string{resourceApps}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewAppCollector(dos)
+	})
+}
+
+// An AppCollector is a Prometheus collector for metrics regarding DigitalOcean
+// App Platform apps and their deployments.
+type AppCollector struct {
+	Info                  *prometheus.Desc
+	ActiveDeploymentPhase *prometheus.Desc
+	LastDeploymentCreated *prometheus.Desc
+	LastDeploymentPhase   *prometheus.Desc
+	LastDeploymentSuccess *prometheus.Desc
+	Deployments           *prometheus.Desc
+	Components            *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that AppCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &AppCollector{}
+
+// NewAppCollector creates a new AppCollector which collects metrics about the
+// App Platform apps in a DigitalOcean account.
+func NewAppCollector(dos DigitalOceanSource) *AppCollector {
+	return &AppCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "app", "info"),
+			"Information about an App Platform app, always 1.",
+			[]string{"id", "name", "region", "tier"},
+			nil,
+		),
+		ActiveDeploymentPhase: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "app", "active_deployment_phase"),
+			"Phase of the active deployment of an app, always 1.",
+			[]string{"id", "phase"},
+			nil,
+		),
+		LastDeploymentCreated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "app", "last_deployment_timestamp_seconds"),
+			"Time at which the most recent deployment of an app was created, in seconds since the Unix epoch.",
+			[]string{"id"},
+			nil,
+		),
+		LastDeploymentPhase: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "app", "last_deployment_phase"),
+			"Phase of the most recent deployment of an app, always 1.",
+			[]string{"id", "phase"},
+			nil,
+		),
+		LastDeploymentSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "app", "last_deployment_success"),
+			"Whether the most recent finished deployment of an app succeeded.",
+			[]string{"id"},
+			nil,
+		),
+		Deployments: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "app", "deployments"),
+			"Number of recent deployments of an app by phase.",
+			[]string{"id", "phase"},
+			nil,
+		),
+		Components: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "app", "components"),
+			"Number of components of an app by type.",
+			[]string{"id", "type"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *AppCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.ActiveDeploymentPhase,
+		c.LastDeploymentCreated,
+		c.LastDeploymentPhase,
+		c.LastDeploymentSuccess,
+		c.Deployments,
+		c.Components,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to App Platform
+// apps to the provided prometheus Metric channel.
+func (c *AppCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, app := range c.dos.Snapshot().AppList() {
+		c.collectApp(ch, app)
+	}
+}
+
+func (c *AppCollector) collectApp(ch chan<- prometheus.Metric, app App) {
+	a := app.app
+
+	var name, region string
+	if a.Spec != nil {
+		name = a.Spec.Name
+		region = a.Spec.Region
+	}
+	if a.Region != nil {
+		region = a.Region.Slug
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1,
+		a.ID,
+		name,
+		region,
+		a.TierSlug,
+	)
+
+	if a.ActiveDeployment != nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.ActiveDeploymentPhase,
+			prometheus.GaugeValue,
+			1,
+			a.ID,
+			string(a.ActiveDeployment.Phase),
+		)
+	}
+
+	if last := lastDeployment(app.deployments); last != nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.LastDeploymentCreated,
+			prometheus.GaugeValue,
+			float64(last.CreatedAt.Unix()),
+			a.ID,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.LastDeploymentPhase,
+			prometheus.GaugeValue,
+			1,
+			a.ID,
+			string(last.Phase),
+		)
+	}
+
+	if success, ok := lastDeploymentSuccess(app.deployments); ok {
+		ch <- prometheus.MustNewConstMetric(
+			c.LastDeploymentSuccess,
+			prometheus.GaugeValue,
+			boolToFloat64(success),
+			a.ID,
+		)
+	}
+
+	phases := make(map[godo.DeploymentPhase]int)
+	for _, d := range app.deployments {
+		phases[d.Phase]++
+	}
+	for phase, count := range phases {
+		ch <- prometheus.MustNewConstMetric(
+			c.Deployments,
+			prometheus.GaugeValue,
+			float64(count),
+			a.ID,
+			string(phase),
+		)
+	}
+
+	if a.Spec == nil {
+		return
+	}
+
+	components := []struct {
+		componentType string
+		count         int
+	}{
+		{"service", len(a.Spec.Services)},
+		{"worker", len(a.Spec.Workers)},
+		{"static_site", len(a.Spec.StaticSites)},
+		{"job", len(a.Spec.Jobs)},
+		{"function", len(a.Spec.Functions)},
+	}
+	for _, comp := range components {
+		ch <- prometheus.MustNewConstMetric(
+			c.Components,
+			prometheus.GaugeValue,
+			float64(comp.count),
+			a.ID,
+			comp.componentType,
+		)
+	}
+}
+
+// lastDeployment returns the most recently created of the deployments, or nil
+// if there are none.
+func lastDeployment(deployments []*godo.Deployment) *godo.Deployment {
+	var last *godo.Deployment
+	for _, d := range deployments {
+		if last == nil || d.CreatedAt.After(last.CreatedAt) {
+			last = d
+		}
+	}
+	return last
+}
+
+// lastDeploymentSuccess reports whether the most recently created of the
+// deployments which have finished succeeded. Deployments which were
+// superseded by a later one had succeeded.
+func lastDeploymentSuccess(deployments []*godo.Deployment) (bool, bool) {
+	var last *godo.Deployment
+	for _, d := range deployments {
+		switch d.Phase {
+		case godo.DeploymentPhase_Active, godo.DeploymentPhase_Superseded,
+			godo.DeploymentPhase_Error, godo.DeploymentPhase_Canceled:
+		default:
+			continue
+		}
+		if last == nil || d.CreatedAt.After(last.CreatedAt) {
+			last = d
+		}
+	}
+	if last == nil {
+		return false, false
+	}
+
+	return last.Phase == godo.DeploymentPhase_Active || last.Phase == godo.DeploymentPhase_Superseded, true
+}
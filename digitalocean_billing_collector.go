@@ -0,0 +1,131 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceBilling, false, []string{resourceBilling}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewBillingCollector(dos)
+	})
+}
+
+// A BillingCollector is a Prometheus collector for metrics regarding the
+// balance and invoices of a DigitalOcean account.
+type BillingCollector struct {
+	MonthToDateUsage   *prometheus.Desc
+	AccountBalance     *prometheus.Desc
+	MonthToDateBalance *prometheus.Desc
+	GeneratedAt        *prometheus.Desc
+	InvoiceAmount      *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that BillingCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &BillingCollector{}
+
+// NewBillingCollector creates a new BillingCollector which collects metrics
+// about the billing of a DigitalOcean account.
+func NewBillingCollector(dos DigitalOceanSource) *BillingCollector {
+	return &BillingCollector{
+		MonthToDateUsage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "billing", "month_to_date_usage_dollars"),
+			"Amount used in the current billing period in US dollars.",
+			nil,
+			nil,
+		),
+		AccountBalance: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "billing", "account_balance_dollars"),
+			"Balance of the account as of the most recent invoice in US dollars.",
+			nil,
+			nil,
+		),
+		MonthToDateBalance: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "billing", "month_to_date_balance_dollars"),
+			"Balance of the account including the current billing period in US dollars.",
+			nil,
+			nil,
+		),
+		GeneratedAt: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "billing", "generated_timestamp_seconds"),
+			"Time at which the balance was generated, in seconds since the Unix epoch.",
+			nil,
+			nil,
+		),
+		InvoiceAmount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "billing", "invoice_amount_dollars"),
+			"Amount of each recent invoice in US dollars.",
+			[]string{"invoice_uuid", "invoice_period"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *BillingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.MonthToDateUsage,
+		c.AccountBalance,
+		c.MonthToDateBalance,
+		c.GeneratedAt,
+		c.InvoiceAmount,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to billing to
+// the provided prometheus Metric channel.
+func (c *BillingCollector) Collect(ch chan<- prometheus.Metric) {
+	billing := c.dos.Snapshot().Billing()
+	if billing == nil {
+		return
+	}
+
+	if balance := billing.balance; balance != nil {
+		amounts := []struct {
+			desc   *prometheus.Desc
+			amount string
+		}{
+			{c.MonthToDateUsage, balance.MonthToDateUsage},
+			{c.AccountBalance, balance.AccountBalance},
+			{c.MonthToDateBalance, balance.MonthToDateBalance},
+		}
+
+		for _, a := range amounts {
+			if value, ok := parseAmount(a.amount); ok {
+				ch <- prometheus.MustNewConstMetric(
+					a.desc,
+					prometheus.GaugeValue,
+					value,
+				)
+			}
+		}
+
+		if !balance.GeneratedAt.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.GeneratedAt,
+				prometheus.GaugeValue,
+				float64(balance.GeneratedAt.Unix()),
+			)
+		}
+	}
+
+	for _, invoice := range billing.invoices {
+		if value, ok := parseAmount(invoice.Amount); ok {
+			ch <- prometheus.MustNewConstMetric(
+				c.InvoiceAmount,
+				prometheus.GaugeValue,
+				value,
+				invoice.InvoiceUUID,
+				invoice.InvoicePeriod,
+			)
+		}
+	}
+}
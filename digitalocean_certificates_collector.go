@@ -0,0 +1,146 @@
+package digitaloceanexporter
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	resources := []string{resourceCertificates, resourceLoadBalancers}
+	registerCollector(resourceCertificates, true, resources, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewCertificateCollector(dos)
+	})
+}
+
+// A CertificateCollector is a Prometheus collector for metrics regarding the
+// TLS certificates of a DigitalOcean account.
+type CertificateCollector struct {
+	Info     *prometheus.Desc
+	NotAfter *prometheus.Desc
+	DNSNames *prometheus.Desc
+	InUse    *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that CertificateCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &CertificateCollector{}
+
+// NewCertificateCollector creates a new CertificateCollector which collects
+// metrics about the certificates in a DigitalOcean account.
+func NewCertificateCollector(dos DigitalOceanSource) *CertificateCollector {
+	labels := []string{"id"}
+
+	return &CertificateCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "certificate", "info"),
+			"Information about a certificate, always 1.",
+			[]string{"id", "name", "type", "state"},
+			nil,
+		),
+		NotAfter: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "certificate", "not_after_timestamp_seconds"),
+			"Time at which a certificate expires, in seconds since the Unix epoch.",
+			labels,
+			nil,
+		),
+		DNSNames: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "certificate", "dns_names"),
+			"Number of DNS names a certificate is valid for.",
+			labels,
+			nil,
+		),
+		InUse: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "certificate", "in_use"),
+			"Whether a certificate is used by a forwarding rule of any Load Balancer.",
+			labels,
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *CertificateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.NotAfter,
+		c.DNSNames,
+		c.InUse,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to certificates
+// to the provided prometheus Metric channel.
+func (c *CertificateCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	// Whether a certificate is in use is only known once the Load Balancers
+	// are, as otherwise every certificate would appear to be unused.
+	var inUse map[string]bool
+	if s.available(resourceLoadBalancers) {
+		inUse = make(map[string]bool)
+		for _, lb := range s.LoadBalancerList() {
+			for _, rule := range lb.ForwardingRules {
+				if rule.CertificateID != "" {
+					inUse[rule.CertificateID] = true
+				}
+			}
+		}
+	}
+
+	for _, cert := range s.CertificateList() {
+		c.collectCertificate(ch, cert, inUse)
+	}
+}
+
+func (c *CertificateCollector) collectCertificate(ch chan<- prometheus.Metric, cert godo.Certificate, inUse map[string]bool) {
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1,
+		cert.ID,
+		cert.Name,
+		cert.Type,
+		cert.State,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DNSNames,
+		prometheus.GaugeValue,
+		float64(len(cert.DNSNames)),
+		cert.ID,
+	)
+
+	// Certificates which have not been issued yet have no expiry.
+	if cert.NotAfter != "" {
+		notAfter, err := time.Parse(time.RFC3339, cert.NotAfter)
+		if err != nil {
+			logrus.WithField("certificate", cert.ID).WithError(err).Debugln("Cannot parse expiry of certificate")
+		} else {
+			ch <- prometheus.MustNewConstMetric(
+				c.NotAfter,
+				prometheus.GaugeValue,
+				float64(notAfter.Unix()),
+				cert.ID,
+			)
+		}
+	}
+
+	if inUse != nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.InUse,
+			prometheus.GaugeValue,
+			boolToFloat64(inUse[cert.ID]),
+			cert.ID,
+		)
+	}
+}
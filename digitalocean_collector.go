@@ -2,35 +2,38 @@ package digitaloceanexporter
 
 import (
 	"time"
-	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// A DigitalOceanSource is an interface which can retrieve information about a
-// resources in a DigitalOcean account. It is implemented by
+// A DigitalOceanSource is an interface which can retrieve a consistent view of
+// the resources in a DigitalOcean account. It is implemented by
 // *digitaloceanexporter.DigitalOceanService.
 type DigitalOceanSource interface {
-	Droplets() map[DropletCounter]int
-	FloatingIPs() map[FlipCounter]int
-	LoadBalancers() map[LoadBalancerCounter]int
-	Tags() map[TagCounter]int
-	Volumes() map[VolumeCounter]int
-
-	QueryDuration() time.Duration
+	Snapshot() *Snapshot
 }
 
-// A DigitalOceanCollector is a Prometheus collector for metrics regarding
-// DigitalOcean.
+// A DigitalOceanCollector is a Prometheus collector for metrics regarding the
+// exporter's own refreshes of data from the DigitalOcean API.
 type DigitalOceanCollector struct {
-	Droplets      *prometheus.Desc
-	FloatingIPs   *prometheus.Desc
-	LoadBalancers *prometheus.Desc
-	Tags          *prometheus.Desc
-	Volumes       *prometheus.Desc
-
 	QueryDuration *prometheus.Desc
 
+	Up                    *prometheus.Desc
+	LastRefreshSuccess    *prometheus.Desc
+	LastSuccessfulRefresh *prometheus.Desc
+	RefreshErrors         *prometheus.Desc
+	DataStale             *prometheus.Desc
+	DataAge               *prometheus.Desc
+	RefreshDuration       *prometheus.Desc
+	RefreshTimeouts       *prometheus.Desc
+
+	RateLimit          *prometheus.Desc
+	RateLimitRemaining *prometheus.Desc
+	RateLimitReset     *prometheus.Desc
+
+	RefreshRequests *prometheus.Desc
+	RefreshInterval *prometheus.Desc
+
 	dos DigitalOceanSource
 }
 
@@ -38,141 +41,254 @@ type DigitalOceanCollector struct {
 var _ prometheus.Collector = &DigitalOceanCollector{}
 
 // NewDigitalOceanCollector creates a new DigitalOceanCollector which collects
-// metrics about resources in a DigitalOcean account.
+// metrics about the health of the refreshes from the DigitalOcean API.
 func NewDigitalOceanCollector(dos DigitalOceanSource) *DigitalOceanCollector {
 	return &DigitalOceanCollector{
-		Droplets: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "droplets", "count"),
-			"Number of Droplets by region, size, and status.",
-			[]string{"region", "size", "status", "price_hourly", "price_monthly", "tags"},
+		QueryDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "query_duration", "seconds"),
+			"Time elapsed while querying the DigitalOcean API in seconds.",
+			[]string{},
+			nil,
+		),
+
+		Up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "up"),
+			"Whether the most recent refresh of every resource type from the DigitalOcean API was successful.",
+			[]string{},
+			nil,
+		),
+		LastRefreshSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "last_refresh", "success"),
+			"Whether the most recent refresh of a resource type was successful.",
+			[]string{"resource"},
+			nil,
+		),
+		LastSuccessfulRefresh: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "last_successful_refresh", "timestamp_seconds"),
+			"Unix timestamp of the most recent successful refresh of a resource type.",
+			[]string{"resource"},
+			nil,
+		),
+		RefreshErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "refresh_errors", "total"),
+			"Number of failed refreshes by resource type and error class.",
+			[]string{"resource", "class"},
 			nil,
 		),
-		FloatingIPs: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "floating_ips", "count"),
-			"Number of Floating IPs by region and status.",
-			[]string{"region", "status"},
+		DataStale: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "data", "stale"),
+			"Whether the data served for a resource type is retained from an earlier refresh because the most recent one failed.",
+			[]string{"resource"},
 			nil,
 		),
-		LoadBalancers: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "load_balancers", "count"),
-			"Number of Load Balancers by region and status.",
-			[]string{"region", "status"},
+		DataAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "data_age", "seconds"),
+			"Age of the data served for a resource type in seconds.",
+			[]string{"resource"},
 			nil,
 		),
-		Tags: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "tags", "count"),
-			"Count of tagged resources by name and resource type.",
-			[]string{"name", "resource_type"},
+		RefreshDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "refresh_duration", "seconds"),
+			"Time elapsed during the most recent refresh of a resource type in seconds.",
+			[]string{"resource"},
 			nil,
 		),
-		Volumes: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "volumes", "count"),
-			"Number of Volumes by region, size in GiB, and status.",
-			[]string{"region", "size", "status"},
+		RefreshTimeouts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "refresh_timeouts", "total"),
+			"Number of refreshes of a resource type aborted by a timeout.",
+			[]string{"resource"},
 			nil,
 		),
 
-		QueryDuration: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "query_duration", "seconds"),
-			"Time elapsed while querying the DigitalOcean API in seconds.",
+		RateLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "api", "rate_limit"),
+			"Number of requests the DigitalOcean API token may make per hour.",
+			[]string{},
+			nil,
+		),
+		RateLimitRemaining: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "api_rate_limit", "remaining"),
+			"Number of requests remaining in the current DigitalOcean API rate limit window.",
+			[]string{},
+			nil,
+		),
+		RateLimitReset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "api_rate_limit", "reset_timestamp_seconds"),
+			"Unix timestamp at which the DigitalOcean API rate limit window resets.",
 			[]string{},
 			nil,
 		),
 
+		RefreshRequests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "refresh", "requests"),
+			"Number of requests made against the DigitalOcean API by the most recent refresh of a resource type.",
+			[]string{"resource"},
+			nil,
+		),
+		RefreshInterval: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "refresh_interval", "seconds"),
+			"Effective time between subsequent refreshes of a resource type in seconds.",
+			[]string{"resource"},
+			nil,
+		),
+
 		dos: dos,
 	}
 }
 
-// collect begins a metrics collection task for all metrics related to
-// resources in a DigitalOcean account.
+// collect begins a metrics collection task for all metrics related to the
+// refreshes from the DigitalOcean API.
 func (c *DigitalOceanCollector) collect(ch chan<- prometheus.Metric) {
-	c.collectDropletCounts(ch)
-	c.collectFipsCounts(ch)
-	c.collectLoadBalancerCounts(ch)
-	c.collectTagCounts(ch)
-	c.collectVolumeCounts(ch)
+	s := c.dos.Snapshot()
 
-	c.collectQueryDuration(ch)
+	c.collectQueryDuration(ch, s)
+	c.collectRefreshStatus(ch, s)
+	c.collectRefreshErrors(ch, s)
+	c.collectRateLimit(ch, s)
 }
 
-func (c *DigitalOceanCollector) collectDropletCounts(ch chan<- prometheus.Metric) {
-	for d, count := range c.dos.Droplets() {
+func (c *DigitalOceanCollector) collectQueryDuration(ch chan<- prometheus.Metric, s *Snapshot) {
+	ch <- prometheus.MustNewConstMetric(
+		c.QueryDuration,
+		prometheus.GaugeValue,
+		s.QueryDuration().Seconds(),
+	)
+}
+
+func (c *DigitalOceanCollector) collectRefreshStatus(ch chan<- prometheus.Metric, s *Snapshot) {
+	statuses := s.RefreshStatus()
+	up := len(statuses) > 0
+
+	for resource, status := range statuses {
+		if !status.success {
+			up = false
+		}
+
 		ch <- prometheus.MustNewConstMetric(
-			c.Droplets,
+			c.LastRefreshSuccess,
 			prometheus.GaugeValue,
-			float64(count),
-			d.region,
-			d.size,
-			d.status,
-			strconv.FormatFloat(d.price_hourly, 'f', 6, 64),
-			strconv.FormatFloat(d.price_monthly, 'f', 2, 64),
-			d.tags,
+			boolToFloat64(status.success),
+			resource,
 		)
-	}
-}
 
-func (c *DigitalOceanCollector) collectFipsCounts(ch chan<- prometheus.Metric) {
-	for fip, count := range c.dos.FloatingIPs() {
 		ch <- prometheus.MustNewConstMetric(
-			c.FloatingIPs,
+			c.RefreshDuration,
 			prometheus.GaugeValue,
-			float64(count),
-			fip.region,
-			fip.status,
+			status.duration.Seconds(),
+			resource,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.RefreshRequests,
+			prometheus.GaugeValue,
+			float64(status.requests),
+			resource,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.RefreshInterval,
+			prometheus.GaugeValue,
+			status.interval.Seconds(),
+			resource,
 		)
-	}
-}
 
-func (c *DigitalOceanCollector) collectLoadBalancerCounts(ch chan<- prometheus.Metric) {
-	for lb, count := range c.dos.LoadBalancers() {
 		ch <- prometheus.MustNewConstMetric(
-			c.LoadBalancers,
+			c.DataStale,
 			prometheus.GaugeValue,
-			float64(count),
-			lb.region,
-			lb.status,
+			boolToFloat64(status.stale()),
+			resource,
 		)
+
+		if !status.lastSuccess.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.LastSuccessfulRefresh,
+				prometheus.GaugeValue,
+				float64(status.lastSuccess.Unix()),
+				resource,
+			)
+			ch <- prometheus.MustNewConstMetric(
+				c.DataAge,
+				prometheus.GaugeValue,
+				time.Since(status.lastSuccess).Seconds(),
+				resource,
+			)
+		}
 	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Up,
+		prometheus.GaugeValue,
+		boolToFloat64(up),
+	)
 }
 
-func (c *DigitalOceanCollector) collectTagCounts(ch chan<- prometheus.Metric) {
-	for t, count := range c.dos.Tags() {
+func (c *DigitalOceanCollector) collectRefreshErrors(ch chan<- prometheus.Metric, s *Snapshot) {
+	for e, count := range s.RefreshErrors() {
 		ch <- prometheus.MustNewConstMetric(
-			c.Tags,
-			prometheus.GaugeValue,
+			c.RefreshErrors,
+			prometheus.CounterValue,
 			float64(count),
-			t.name,
-			t.resourceType,
+			e.resource,
+			e.class,
 		)
 	}
-}
 
-func (c *DigitalOceanCollector) collectVolumeCounts(ch chan<- prometheus.Metric) {
-	for v, count := range c.dos.Volumes() {
+	for resource, count := range s.RefreshTimeouts() {
 		ch <- prometheus.MustNewConstMetric(
-			c.Volumes,
-			prometheus.GaugeValue,
+			c.RefreshTimeouts,
+			prometheus.CounterValue,
 			float64(count),
-			v.region,
-			v.size,
-			v.status,
+			resource,
 		)
 	}
 }
 
-func (c *DigitalOceanCollector) collectQueryDuration(ch chan<- prometheus.Metric) {
+func (c *DigitalOceanCollector) collectRateLimit(ch chan<- prometheus.Metric, s *Snapshot) {
+	rate := s.Rate()
+	if rate.Limit == 0 {
+		return
+	}
+
 	ch <- prometheus.MustNewConstMetric(
-		c.QueryDuration,
+		c.RateLimit,
+		prometheus.GaugeValue,
+		float64(rate.Limit),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.RateLimitRemaining,
+		prometheus.GaugeValue,
+		float64(rate.Remaining),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.RateLimitReset,
 		prometheus.GaugeValue,
-		c.dos.QueryDuration().Seconds(),
+		float64(rate.Reset.Unix()),
 	)
 }
 
+func boolToFloat64(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // Describe sends the descriptors of each metric over to the provided channel.
 // The corresponding metric values are sent separately.
 func (c *DigitalOceanCollector) Describe(ch chan<- *prometheus.Desc) {
 	ds := []*prometheus.Desc{
-		c.Droplets,
+		c.QueryDuration,
+		c.Up,
+		c.LastRefreshSuccess,
+		c.LastSuccessfulRefresh,
+		c.RefreshErrors,
+		c.DataStale,
+		c.DataAge,
+		c.RefreshDuration,
+		c.RefreshTimeouts,
+		c.RateLimit,
+		c.RateLimitRemaining,
+		c.RateLimitReset,
+		c.RefreshRequests,
+		c.RefreshInterval,
 	}
 
 	for _, d := range ds {
@@ -180,8 +296,8 @@ func (c *DigitalOceanCollector) Describe(ch chan<- *prometheus.Desc) {
 	}
 }
 
-// Collect sends the metric values for each metric pertaining to the DigitalOcean
-// resources to the provided prometheus Metric channel.
+// Collect sends the metric values for each metric pertaining to the refreshes
+// from the DigitalOcean API to the provided prometheus Metric channel.
 func (c *DigitalOceanCollector) Collect(ch chan<- prometheus.Metric) {
 	c.collect(ch)
 }
@@ -0,0 +1,247 @@
+package digitaloceanexporter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceDatabases, true, []string{resourceDatabases}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewDatabaseCollector(dos)
+	})
+}
+
+// A DatabaseCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Managed Database clusters.
+type DatabaseCollector struct {
+	Info               *prometheus.Desc
+	Nodes              *prometheus.Desc
+	NextMaintenance    *prometheus.Desc
+	MaintenancePending *prometheus.Desc
+	Replicas           *prometheus.Desc
+	Pools              *prometheus.Desc
+	PoolSize           *prometheus.Desc
+	FirewallRules      *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that DatabaseCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &DatabaseCollector{}
+
+// NewDatabaseCollector creates a new DatabaseCollector which collects metrics
+// about the database clusters in a DigitalOcean account.
+func NewDatabaseCollector(dos DigitalOceanSource) *DatabaseCollector {
+	labels := []string{"id"}
+
+	return &DatabaseCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "info"),
+			"Information about a database cluster, always 1.",
+			[]string{"id", "name", "engine", "version", "region", "size", "status"},
+			nil,
+		),
+		Nodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "nodes"),
+			"Number of nodes of a database cluster.",
+			labels,
+			nil,
+		),
+		NextMaintenance: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "next_maintenance_timestamp_seconds"),
+			"Time at which the next maintenance window of a database cluster starts, in seconds since the Unix epoch.",
+			labels,
+			nil,
+		),
+		MaintenancePending: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "maintenance_pending"),
+			"Whether maintenance is pending for a database cluster.",
+			labels,
+			nil,
+		),
+		Replicas: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "replicas"),
+			"Number of read replicas of a database cluster by status.",
+			[]string{"id", "status"},
+			nil,
+		),
+		Pools: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "pools"),
+			"Number of connection pools of a database cluster.",
+			labels,
+			nil,
+		),
+		PoolSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "pool_size"),
+			"Number of connections of a connection pool.",
+			[]string{"id", "pool", "db", "mode"},
+			nil,
+		),
+		FirewallRules: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "database", "firewall_rules"),
+			"Number of trusted sources of a database cluster by type.",
+			[]string{"id", "type"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *DatabaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.Nodes,
+		c.NextMaintenance,
+		c.MaintenancePending,
+		c.Replicas,
+		c.Pools,
+		c.PoolSize,
+		c.FirewallRules,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to database
+// clusters to the provided prometheus Metric channel.
+func (c *DatabaseCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, cluster := range c.dos.Snapshot().DatabaseList() {
+		c.collectDatabase(ch, cluster, now)
+	}
+}
+
+func (c *DatabaseCollector) collectDatabase(ch chan<- prometheus.Metric, cluster DatabaseCluster, now time.Time) {
+	db := cluster.database
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1,
+		db.ID,
+		db.Name,
+		db.EngineSlug,
+		db.VersionSlug,
+		db.RegionSlug,
+		db.SizeSlug,
+		db.Status,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.Nodes,
+		prometheus.GaugeValue,
+		float64(db.NumNodes),
+		db.ID,
+	)
+
+	if w := db.MaintenanceWindow; w != nil {
+		if next, ok := nextMaintenanceWindow(w, now); ok {
+			ch <- prometheus.MustNewConstMetric(
+				c.NextMaintenance,
+				prometheus.GaugeValue,
+				float64(next.Unix()),
+				db.ID,
+			)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.MaintenancePending,
+			prometheus.GaugeValue,
+			boolToFloat64(w.Pending),
+			db.ID,
+		)
+	}
+
+	replicas := make(map[string]int)
+	for _, r := range cluster.replicas {
+		replicas[r.Status]++
+	}
+	for status, count := range replicas {
+		ch <- prometheus.MustNewConstMetric(
+			c.Replicas,
+			prometheus.GaugeValue,
+			float64(count),
+			db.ID,
+			status,
+		)
+	}
+
+	if poolEngines[db.EngineSlug] {
+		ch <- prometheus.MustNewConstMetric(
+			c.Pools,
+			prometheus.GaugeValue,
+			float64(len(cluster.pools)),
+			db.ID,
+		)
+	}
+	for _, pool := range cluster.pools {
+		ch <- prometheus.MustNewConstMetric(
+			c.PoolSize,
+			prometheus.GaugeValue,
+			float64(pool.Size),
+			db.ID,
+			pool.Name,
+			pool.Database,
+			pool.Mode,
+		)
+	}
+
+	rules := make(map[string]int)
+	for _, rule := range cluster.firewallRules {
+		rules[rule.Type]++
+	}
+	for ruleType, count := range rules {
+		ch <- prometheus.MustNewConstMetric(
+			c.FirewallRules,
+			prometheus.GaugeValue,
+			float64(count),
+			db.ID,
+			ruleType,
+		)
+	}
+}
+
+// weekdays maps the days of maintenance windows to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// nextMaintenanceWindow returns the start of the first maintenance window
+// after now. Maintenance windows recur weekly on a day and hour in UTC.
+func nextMaintenanceWindow(w *godo.DatabaseMaintenanceWindow, now time.Time) (time.Time, bool) {
+	day, ok := weekdays[strings.ToLower(w.Day)]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	var clock [3]int
+	for i, part := range strings.SplitN(w.Hour, ":", 3) {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, false
+		}
+		clock[i] = value
+	}
+
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), clock[0], clock[1], clock[2], 0, time.UTC)
+	next = next.AddDate(0, 0, (int(day)-int(now.Weekday())+7)%7)
+	if next.Before(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+
+	return next, true
+}
@@ -0,0 +1,157 @@
+package digitaloceanexporter
+
+import (
+	"net"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	resources := []string{resourceDomains, resourceDroplets, resourceFloatingIPs, resourceLoadBalancers}
+	registerCollector(resourceDomains, true, resources, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewDomainCollector(dos)
+	})
+}
+
+// A DomainCollector is a Prometheus collector for metrics regarding
+// DigitalOcean domains and their DNS records.
+type DomainCollector struct {
+	Records  *prometheus.Desc
+	MinTTL   *prometheus.Desc
+	Dangling *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that DomainCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &DomainCollector{}
+
+// NewDomainCollector creates a new DomainCollector which collects metrics
+// about the domains in a DigitalOcean account.
+func NewDomainCollector(dos DigitalOceanSource) *DomainCollector {
+	return &DomainCollector{
+		Records: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "domain", "records"),
+			"Number of DNS records by domain and type.",
+			[]string{"domain", "type"},
+			nil,
+		),
+		MinTTL: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "domain", "min_ttl_seconds"),
+			"Lowest TTL of the DNS records of a domain in seconds.",
+			[]string{"domain"},
+			nil,
+		),
+		Dangling: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "domain", "record_dangling"),
+			"Whether an A or AAAA record points to an address of no Droplet, Floating IP or Load Balancer, always 1.",
+			[]string{"domain", "name", "type", "data"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *DomainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Records,
+		c.MinTTL,
+		c.Dangling,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to domains to
+// the provided prometheus Metric channel.
+func (c *DomainCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	for r, count := range s.DomainRecords() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Records,
+			prometheus.GaugeValue,
+			float64(count),
+			r.domain,
+			r.recordType,
+		)
+	}
+
+	for domain, ttl := range s.DomainMinTTLs() {
+		ch <- prometheus.MustNewConstMetric(
+			c.MinTTL,
+			prometheus.GaugeValue,
+			float64(ttl),
+			domain,
+		)
+	}
+
+	// Records can only be recognised as dangling once every resource type
+	// which owns addresses is known, as otherwise every record pointing to
+	// a resource of a missing type would appear to be dangling.
+	for _, resource := range []string{resourceDomains, resourceDroplets, resourceFloatingIPs, resourceLoadBalancers} {
+		if !s.available(resource) {
+			return
+		}
+	}
+
+	addresses := knownAddresses(s)
+	for _, r := range s.AddressRecords() {
+		if addresses[normalizeAddress(r.data)] {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.Dangling,
+			prometheus.GaugeValue,
+			1,
+			r.domain,
+			r.name,
+			r.recordType,
+			r.data,
+		)
+	}
+}
+
+// knownAddresses returns the addresses of the Droplets, Floating IPs and Load
+// Balancers of a Snapshot.
+func knownAddresses(s *Snapshot) map[string]bool {
+	addresses := make(map[string]bool)
+
+	for _, d := range s.DropletList() {
+		if d.Networks == nil {
+			continue
+		}
+		for _, n := range d.Networks.V4 {
+			addresses[normalizeAddress(n.IPAddress)] = true
+		}
+		for _, n := range d.Networks.V6 {
+			addresses[normalizeAddress(n.IPAddress)] = true
+		}
+	}
+
+	for _, fip := range s.FloatingIPList() {
+		addresses[normalizeAddress(fip.IP)] = true
+	}
+
+	for _, lb := range s.LoadBalancerList() {
+		addresses[normalizeAddress(lb.IP)] = true
+	}
+
+	return addresses
+}
+
+// normalizeAddress returns the canonical form of an IP address, so that IPv6
+// addresses written differently compare equal.
+func normalizeAddress(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return address
+	}
+	return ip.String()
+}
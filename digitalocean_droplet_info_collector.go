@@ -0,0 +1,182 @@
+package digitaloceanexporter
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector("droplet_info", false, []string{resourceDroplets}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewDropletInfoCollector(dos)
+	})
+}
+
+// A DropletInfoCollector is a Prometheus collector for metrics regarding
+// individual DigitalOcean Droplets. It exports one series per Droplet for
+// each metric, so that Droplets can be joined with other targets by name or
+// address.
+type DropletInfoCollector struct {
+	Info        *prometheus.Desc
+	VCPUs       *prometheus.Desc
+	MemoryBytes *prometheus.Desc
+	DiskBytes   *prometheus.Desc
+	Created     *prometheus.Desc
+	Backups     *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that DropletInfoCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &DropletInfoCollector{}
+
+// NewDropletInfoCollector creates a new DropletInfoCollector which collects
+// metrics about each Droplet in a DigitalOcean account.
+func NewDropletInfoCollector(dos DigitalOceanSource) *DropletInfoCollector {
+	labels := []string{"id"}
+
+	return &DropletInfoCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplet", "info"),
+			"Information about a Droplet, always 1.",
+			[]string{
+				"id", "name", "region", "size", "image", "distribution", "vpc_uuid",
+				"public_ipv4", "private_ipv4", "ipv6", "status",
+			},
+			nil,
+		),
+		VCPUs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplet", "vcpus"),
+			"Number of virtual CPUs of a Droplet.",
+			labels,
+			nil,
+		),
+		MemoryBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplet", "memory_bytes"),
+			"Memory of a Droplet in bytes.",
+			labels,
+			nil,
+		),
+		DiskBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplet", "disk_bytes"),
+			"Disk size of a Droplet in bytes.",
+			labels,
+			nil,
+		),
+		Created: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplet", "created_timestamp_seconds"),
+			"Time at which a Droplet was created, in seconds since the Unix epoch.",
+			labels,
+			nil,
+		),
+		Backups: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplet", "backups"),
+			"Number of backups available for a Droplet.",
+			labels,
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *DropletInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.VCPUs,
+		c.MemoryBytes,
+		c.DiskBytes,
+		c.Created,
+		c.Backups,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to individual
+// Droplets to the provided prometheus Metric channel.
+func (c *DropletInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, d := range c.dos.Snapshot().DropletList() {
+		id := strconv.Itoa(d.ID)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.Info,
+			prometheus.GaugeValue,
+			1,
+			dropletInfoLabels(&d)...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.VCPUs,
+			prometheus.GaugeValue,
+			float64(d.Vcpus),
+			id,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.MemoryBytes,
+			prometheus.GaugeValue,
+			float64(d.Memory)*1024*1024,
+			id,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.DiskBytes,
+			prometheus.GaugeValue,
+			float64(d.Disk)*1024*1024*1024,
+			id,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.Backups,
+			prometheus.GaugeValue,
+			float64(len(d.BackupIDs)),
+			id,
+		)
+
+		if created, err := time.Parse(time.RFC3339, d.Created); err == nil {
+			ch <- prometheus.MustNewConstMetric(
+				c.Created,
+				prometheus.GaugeValue,
+				float64(created.Unix()),
+				id,
+			)
+		}
+	}
+}
+
+// dropletInfoLabels returns the values of the labels of the info metric of a
+// Droplet. Attributes which are not set are reported as empty strings.
+func dropletInfoLabels(d *godo.Droplet) []string {
+	var region, size, image, distribution string
+	if d.Region != nil {
+		region = d.Region.Slug
+	}
+	if d.Size != nil {
+		size = d.Size.Slug
+	}
+	if d.Image != nil {
+		image = d.Image.Slug
+		distribution = d.Image.Distribution
+	}
+
+	publicIPv4, _ := d.PublicIPv4()
+	privateIPv4, _ := d.PrivateIPv4()
+	ipv6, _ := d.PublicIPv6()
+
+	return []string{
+		strconv.Itoa(d.ID),
+		d.Name,
+		region,
+		size,
+		image,
+		distribution,
+		d.VPCUUID,
+		publicIPv4,
+		privateIPv4,
+		ipv6,
+		d.Status,
+	}
+}
@@ -0,0 +1,89 @@
+package digitaloceanexporter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tagValueSeparator separates the values of promoted tags held in a single
+// counter field. DigitalOcean does not allow commas in tag names.
+const tagValueSeparator = ","
+
+// invalidLabelChars matches the characters of a tag name which may not be
+// used in a Prometheus label name.
+var invalidLabelChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// TagConfig controls how the tags of Droplets are turned into labels.
+type TagConfig struct {
+	// Include limits the tags label to the tags it matches. All tags are
+	// kept when it is nil.
+	Include *regexp.Regexp
+
+	// Promote names tags which are exported as a label of their own,
+	// named tag_<name>. A key:value tag such as env:production sets the
+	// tag_env label to its value, and a plain tag sets its label to "true".
+	Promote []string
+}
+
+// labelNames returns the names of the labels of the promoted tags.
+func (c TagConfig) labelNames() []string {
+	names := make([]string, len(c.Promote))
+	for i, name := range c.Promote {
+		names[i] = "tag_" + invalidLabelChars.ReplaceAllString(name, "_")
+	}
+	return names
+}
+
+// Validate checks that every promoted tag is named and yields a label name
+// which no other promoted tag does.
+func (c TagConfig) Validate() error {
+	seen := make(map[string]string)
+	for i, label := range c.labelNames() {
+		name := c.Promote[i]
+		if name == "" {
+			return fmt.Errorf("promoted tag names cannot be empty")
+		}
+		if other, ok := seen[label]; ok {
+			return fmt.Errorf("promoted tags %q and %q both map to label %q", other, name, label)
+		}
+		seen[label] = name
+	}
+	return nil
+}
+
+// labels returns the value of the tags label for the given tags, which is the
+// sorted list of included tags, and the values of the promoted tags joined by
+// tagValueSeparator.
+func (c TagConfig) labels(tags []string) (string, string) {
+	included := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if c.Include == nil || c.Include.MatchString(tag) {
+			included = append(included, tag)
+		}
+	}
+	sort.Strings(included)
+
+	values := make([]string, len(c.Promote))
+	for i, name := range c.Promote {
+		for _, tag := range tags {
+			switch {
+			case tag == name:
+				values[i] = "true"
+			case strings.HasPrefix(tag, name+":"):
+				values[i] = strings.TrimPrefix(tag, name+":")
+			}
+		}
+	}
+
+	return strings.Join(included, ","), strings.Join(values, tagValueSeparator)
+}
+
+// splitTagValues splits the values of the promoted tags joined by labels.
+func (c TagConfig) splitTagValues(values string) []string {
+	if len(c.Promote) == 0 {
+		return nil
+	}
+	return strings.Split(values, tagValueSeparator)
+}
@@ -0,0 +1,117 @@
+package digitaloceanexporter
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceDroplets, true, []string{resourceDroplets}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewDropletCollector(dos)
+	})
+}
+
+// A DropletCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Droplets.
+type DropletCollector struct {
+	Droplets    *prometheus.Desc
+	CostHourly  *prometheus.Desc
+	CostMonthly *prometheus.Desc
+	Features    *prometheus.Desc
+
+	dos  DigitalOceanSource
+	tags TagConfig
+}
+
+// Verify that DropletCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &DropletCollector{}
+
+// NewDropletCollector creates a new DropletCollector which collects metrics
+// about the Droplets in a DigitalOcean account.
+// The labels of promoted tags are added to those of every metric.
+func NewDropletCollector(dos DigitalOceanSource) *DropletCollector {
+	tags := dos.Snapshot().DropletTags()
+	tagLabels := tags.labelNames()
+
+	return &DropletCollector{
+		Droplets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplets", "count"),
+			"Number of Droplets by region, size, status, and whether they are DOKS workers.",
+			append([]string{"region", "size", "status", "doks_worker", "tags"}, tagLabels...),
+			nil,
+		),
+		CostHourly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplets", "cost_hourly_dollars"),
+			"Hourly cost of Droplets by region, size, and tags in US dollars.",
+			append([]string{"region", "size", "tags"}, tagLabels...),
+			nil,
+		),
+		CostMonthly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplets", "cost_monthly_dollars"),
+			"Monthly cost of Droplets by region, size, and tags in US dollars.",
+			append([]string{"region", "size", "tags"}, tagLabels...),
+			nil,
+		),
+
+		Features: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplets", "feature_count"),
+			"Number of Droplets with and without each feature by region and tags.",
+			append([]string{"feature", "enabled", "region", "tags"}, tagLabels...),
+			nil,
+		),
+
+		dos:  dos,
+		tags: tags,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *DropletCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.Droplets
+	ch <- c.CostHourly
+	ch <- c.CostMonthly
+	ch <- c.Features
+}
+
+// Collect sends the metric values for each metric pertaining to Droplets to
+// the provided prometheus Metric channel.
+func (c *DropletCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	for d, count := range s.Droplets() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Droplets,
+			prometheus.GaugeValue,
+			float64(count),
+			append([]string{d.region, d.size, d.status, d.doksWorker, d.tags}, c.tags.splitTagValues(d.promoted)...)...,
+		)
+	}
+
+	for d, cost := range s.DropletCosts() {
+		labels := append([]string{d.region, d.size, d.tags}, c.tags.splitTagValues(d.promoted)...)
+
+		ch <- prometheus.MustNewConstMetric(
+			c.CostHourly,
+			prometheus.GaugeValue,
+			cost.hourly,
+			labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.CostMonthly,
+			prometheus.GaugeValue,
+			cost.monthly,
+			labels...,
+		)
+	}
+
+	for f, count := range s.DropletFeatures() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Features,
+			prometheus.GaugeValue,
+			float64(count),
+			append([]string{f.feature, strconv.FormatBool(f.enabled), f.region, f.tags}, c.tags.splitTagValues(f.promoted)...)...,
+		)
+	}
+}
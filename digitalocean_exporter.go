@@ -1,6 +1,8 @@
 package digitaloceanexporter
 
 import (
+	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -10,6 +12,66 @@ const (
 	namespace = "digitalocean"
 )
 
+// A collectorFactory creates a prometheus.Collector which reads from the
+// provided DigitalOceanSource.
+type collectorFactory func(dos DigitalOceanSource) prometheus.Collector
+
+// A collectorRegistration describes a collector which can be enabled or
+// disabled by name.
+type collectorRegistration struct {
+	enabledByDefault bool
+	resources        []string
+	factory          collectorFactory
+}
+
+// collectorRegistry holds every registered collector, keyed by name.
+var collectorRegistry = make(map[string]collectorRegistration)
+
+// registerCollector makes a collector available under the given name. The
+// resource types it reads are only refreshed while it is enabled.
+func registerCollector(name string, enabledByDefault bool, resources []string, factory collectorFactory) {
+	collectorRegistry[name] = collectorRegistration{
+		enabledByDefault: enabledByDefault,
+		resources:        resources,
+		factory:          factory,
+	}
+}
+
+// Collectors returns the names of all registered collectors, mapped to
+// whether each is enabled by default.
+func Collectors() map[string]bool {
+	collectors := make(map[string]bool, len(collectorRegistry))
+	for name, r := range collectorRegistry {
+		collectors[name] = r.enabledByDefault
+	}
+	return collectors
+}
+
+// CollectorResources returns the resource types which must be refreshed from
+// the DigitalOcean API for the named collectors, in the order returned by
+// Resources.
+func CollectorResources(collectors []string) ([]string, error) {
+	needed := make(map[string]bool)
+	for _, name := range collectors {
+		r, ok := collectorRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+		for _, resource := range r.resources {
+			needed[resource] = true
+		}
+	}
+
+	resources := []string{}
+	for _, resource := range Resources() {
+		if needed[resource] {
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources, nil
+}
+
 // An Exporter is a Prometheus exporter for DigitalOcean metrics.
 // It wraps all DigitalOcean metrics collectors and provides a single global
 // exporter which can serve metrics. It also ensures that the collection
@@ -18,19 +80,36 @@ const (
 // register with Prometheus.
 type Exporter struct {
 	mu         sync.Mutex
+	source     *scrapeSource
 	collectors []prometheus.Collector
 }
 
 // Verify that the Exporter implements the prometheus.Collector interface.
 var _ prometheus.Collector = &Exporter{}
 
-// New creates a new Exporter which collects metrics from one or mote sites.
-func New(s *DigitalOceanService) *Exporter {
-	return &Exporter{
-		collectors: []prometheus.Collector{
-			NewDigitalOceanCollector(s),
-		},
+// New creates a new Exporter which collects metrics using the named
+// collectors, in addition to metrics about the exporter's own refreshes.
+func New(s *DigitalOceanService, collectors []string) (*Exporter, error) {
+	source := &scrapeSource{dos: s}
+
+	names := append([]string(nil), collectors...)
+	sort.Strings(names)
+
+	cs := []prometheus.Collector{
+		NewDigitalOceanCollector(source),
+	}
+	for _, name := range names {
+		r, ok := collectorRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+		cs = append(cs, r.factory(source))
 	}
+
+	return &Exporter{
+		source:     source,
+		collectors: cs,
+	}, nil
 }
 
 // Describe sends all the descriptors of the collectors included to
@@ -43,12 +122,34 @@ func (c *Exporter) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect sends the collected metrics from each of the collectors to
 // prometheus. Collect could be called several times concurrently
-// and thus its run is protected by a single mutex.
+// and thus its run is protected by a single mutex. Every collector reads
+// from the same Snapshot, so a scrape sees a consistent view.
 func (c *Exporter) Collect(ch chan<- prometheus.Metric) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.source.snapshot = c.source.dos.Snapshot()
+	defer func() {
+		c.source.snapshot = nil
+	}()
+
 	for _, cc := range c.collectors {
 		cc.Collect(ch)
 	}
 }
+
+// A scrapeSource is a DigitalOceanSource which serves the same Snapshot to
+// every collector during a single scrape.
+type scrapeSource struct {
+	dos      DigitalOceanSource
+	snapshot *Snapshot
+}
+
+// Snapshot retrieves the Snapshot pinned for the current scrape, or the most
+// recent one outside of a scrape.
+func (s *scrapeSource) Snapshot() *Snapshot {
+	if s.snapshot != nil {
+		return s.snapshot
+	}
+	return s.dos.Snapshot()
+}
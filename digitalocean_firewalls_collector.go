@@ -0,0 +1,308 @@
+package digitaloceanexporter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	resources := []string{resourceFirewalls, resourceDroplets}
+	registerCollector(resourceFirewalls, true, resources, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewFirewallCollector(dos)
+	})
+}
+
+// sensitivePorts are the ports of services which should not be reachable
+// from any address, such as remote shells and databases.
+var sensitivePorts = []int{
+	22,    // SSH
+	23,    // Telnet
+	2375,  // Docker
+	3306,  // MySQL
+	3389,  // RDP
+	5432,  // PostgreSQL
+	5900,  // VNC
+	6379,  // Redis
+	9200,  // Elasticsearch
+	11211, // Memcached
+	27017, // MongoDB
+}
+
+// anyAddresses are the sources of inbound rules which match every address.
+var anyAddresses = map[string]bool{
+	"0.0.0.0/0": true,
+	"::/0":      true,
+}
+
+// A FirewallCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Cloud Firewalls and the Droplets they protect.
+type FirewallCollector struct {
+	Info              *prometheus.Desc
+	Rules             *prometheus.Desc
+	Droplets          *prometheus.Desc
+	Tags              *prometheus.Desc
+	PendingChanges    *prometheus.Desc
+	SensitivePortOpen *prometheus.Desc
+	UncoveredDroplets *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that FirewallCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &FirewallCollector{}
+
+// NewFirewallCollector creates a new FirewallCollector which collects metrics
+// about the Cloud Firewalls in a DigitalOcean account.
+func NewFirewallCollector(dos DigitalOceanSource) *FirewallCollector {
+	labels := []string{"id"}
+
+	return &FirewallCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "firewall", "info"),
+			"Information about a Cloud Firewall, always 1.",
+			[]string{"id", "name", "status"},
+			nil,
+		),
+		Rules: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "firewall", "rules"),
+			"Number of rules of a Cloud Firewall by direction.",
+			[]string{"id", "direction"},
+			nil,
+		),
+		Droplets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "firewall", "droplets"),
+			"Number of Droplets a Cloud Firewall is applied to directly.",
+			labels,
+			nil,
+		),
+		Tags: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "firewall", "tags"),
+			"Number of tags a Cloud Firewall is applied to.",
+			labels,
+			nil,
+		),
+		PendingChanges: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "firewall", "pending_changes"),
+			"Number of changes to a Cloud Firewall which have not been applied yet.",
+			labels,
+			nil,
+		),
+		SensitivePortOpen: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "firewall", "sensitive_port_open"),
+			"Whether a Cloud Firewall allows inbound traffic from any address to a sensitive port, always 1.",
+			[]string{"id", "protocol", "port"},
+			nil,
+		),
+		UncoveredDroplets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "droplets", "without_firewall_count"),
+			"Number of Droplets not covered by any Cloud Firewall by region.",
+			[]string{"region"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *FirewallCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.Rules,
+		c.Droplets,
+		c.Tags,
+		c.PendingChanges,
+		c.SensitivePortOpen,
+		c.UncoveredDroplets,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to Cloud
+// Firewalls to the provided prometheus Metric channel.
+func (c *FirewallCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	firewalls := s.FirewallList()
+	for _, fw := range firewalls {
+		c.collectFirewall(ch, fw)
+	}
+
+	// Coverage can only be determined once both Firewalls and Droplets are
+	// known, as otherwise every Droplet would appear to be unprotected.
+	if !s.available(resourceFirewalls) || !s.available(resourceDroplets) {
+		return
+	}
+
+	uncovered := make(map[string]int)
+	for _, d := range s.DropletList() {
+		var region string
+		if d.Region != nil {
+			region = d.Region.Slug
+		}
+
+		// Regions whose Droplets are all covered are reported as zero.
+		count := uncovered[region]
+		if !firewallCovers(firewalls, d) {
+			count++
+		}
+		uncovered[region] = count
+	}
+
+	for region, count := range uncovered {
+		ch <- prometheus.MustNewConstMetric(
+			c.UncoveredDroplets,
+			prometheus.GaugeValue,
+			float64(count),
+			region,
+		)
+	}
+}
+
+func (c *FirewallCollector) collectFirewall(ch chan<- prometheus.Metric, fw godo.Firewall) {
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1,
+		fw.ID,
+		fw.Name,
+		fw.Status,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.Rules,
+		prometheus.GaugeValue,
+		float64(len(fw.InboundRules)),
+		fw.ID,
+		"inbound",
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.Rules,
+		prometheus.GaugeValue,
+		float64(len(fw.OutboundRules)),
+		fw.ID,
+		"outbound",
+	)
+
+	counts := []struct {
+		desc  *prometheus.Desc
+		count int
+	}{
+		{c.Droplets, len(fw.DropletIDs)},
+		{c.Tags, len(fw.Tags)},
+		{c.PendingChanges, len(fw.PendingChanges)},
+	}
+	for _, cnt := range counts {
+		ch <- prometheus.MustNewConstMetric(
+			cnt.desc,
+			prometheus.GaugeValue,
+			float64(cnt.count),
+			fw.ID,
+		)
+	}
+
+	for protocol, ports := range openSensitivePorts(fw) {
+		for port := range ports {
+			ch <- prometheus.MustNewConstMetric(
+				c.SensitivePortOpen,
+				prometheus.GaugeValue,
+				1,
+				fw.ID,
+				protocol,
+				strconv.Itoa(port),
+			)
+		}
+	}
+}
+
+// firewallCovers reports whether any of the Firewalls applies to the Droplet,
+// either directly or through one of its tags.
+func firewallCovers(firewalls []godo.Firewall, d godo.Droplet) bool {
+	for _, fw := range firewalls {
+		for _, id := range fw.DropletIDs {
+			if id == d.ID {
+				return true
+			}
+		}
+		for _, fwTag := range fw.Tags {
+			for _, tag := range d.Tags {
+				if tag == fwTag {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// openSensitivePorts returns the sensitive ports which the inbound rules of a
+// Firewall open to any address, keyed by protocol.
+func openSensitivePorts(fw godo.Firewall) map[string]map[int]bool {
+	open := make(map[string]map[int]bool)
+
+	for _, rule := range fw.InboundRules {
+		if rule.Protocol != "tcp" && rule.Protocol != "udp" {
+			continue
+		}
+		if rule.Sources == nil || !matchesAnyAddress(rule.Sources.Addresses) {
+			continue
+		}
+
+		low, high, ok := parsePortRange(rule.PortRange)
+		if !ok {
+			continue
+		}
+		for _, port := range sensitivePorts {
+			if port < low || port > high {
+				continue
+			}
+			if open[rule.Protocol] == nil {
+				open[rule.Protocol] = make(map[int]bool)
+			}
+			open[rule.Protocol][port] = true
+		}
+	}
+
+	return open
+}
+
+// matchesAnyAddress reports whether the addresses include one matching every
+// address.
+func matchesAnyAddress(addresses []string) bool {
+	for _, address := range addresses {
+		if anyAddresses[address] {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortRange parses the ports of a Firewall rule, which are a single
+// port, a range such as "8000-9000", or "0" or "all" for every port.
+func parsePortRange(ports string) (int, int, bool) {
+	switch ports {
+	case "", "0", "all":
+		return 1, 65535, true
+	}
+
+	bounds := strings.SplitN(ports, "-", 2)
+	low, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(bounds) == 1 {
+		return low, low, true
+	}
+
+	high, err := strconv.Atoi(bounds[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return low, high, true
+}
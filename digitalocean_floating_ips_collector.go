@@ -0,0 +1,90 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceFloatingIPs, true, []string{resourceFloatingIPs}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewFloatingIPCollector(dos)
+	})
+}
+
+// A FloatingIPCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Floating IPs.
+type FloatingIPCollector struct {
+	FloatingIPs *prometheus.Desc
+	CostHourly  *prometheus.Desc
+	CostMonthly *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that FloatingIPCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &FloatingIPCollector{}
+
+// NewFloatingIPCollector creates a new FloatingIPCollector which collects
+// metrics about the Floating IPs in a DigitalOcean account.
+func NewFloatingIPCollector(dos DigitalOceanSource) *FloatingIPCollector {
+	return &FloatingIPCollector{
+		FloatingIPs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "floating_ips", "count"),
+			"Number of Floating IPs by region and status.",
+			[]string{"region", "status"},
+			nil,
+		),
+		CostHourly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "floating_ips", "cost_hourly_dollars"),
+			"Estimated hourly cost of Floating IPs by region in US dollars.",
+			[]string{"region"},
+			nil,
+		),
+		CostMonthly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "floating_ips", "cost_monthly_dollars"),
+			"Estimated monthly cost of Floating IPs by region in US dollars.",
+			[]string{"region"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *FloatingIPCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.FloatingIPs
+	ch <- c.CostHourly
+	ch <- c.CostMonthly
+}
+
+// Collect sends the metric values for each metric pertaining to Floating IPs
+// to the provided prometheus Metric channel.
+func (c *FloatingIPCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	for fip, count := range s.FloatingIPs() {
+		ch <- prometheus.MustNewConstMetric(
+			c.FloatingIPs,
+			prometheus.GaugeValue,
+			float64(count),
+			fip.region,
+			fip.status,
+		)
+	}
+
+	for region, cost := range s.FloatingIPCosts() {
+		ch <- prometheus.MustNewConstMetric(
+			c.CostHourly,
+			prometheus.GaugeValue,
+			cost.hourly,
+			region,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.CostMonthly,
+			prometheus.GaugeValue,
+			cost.monthly,
+			region,
+		)
+	}
+}
@@ -0,0 +1,62 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceInvoiceItems, false, []string{resourceInvoiceItems}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewInvoiceItemCollector(dos)
+	})
+}
+
+// An InvoiceItemCollector is a Prometheus collector for metrics regarding the
+// items of DigitalOcean invoices.
+type InvoiceItemCollector struct {
+	Amount *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that InvoiceItemCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &InvoiceItemCollector{}
+
+// NewInvoiceItemCollector creates a new InvoiceItemCollector which collects
+// metrics about the items of the most recent invoices of a DigitalOcean
+// account.
+func NewInvoiceItemCollector(dos DigitalOceanSource) *InvoiceItemCollector {
+	return &InvoiceItemCollector{
+		Amount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "invoice_item", "amount_dollars"),
+			"Amount of invoice items by invoice, product, project, group description, and category in US dollars.",
+			[]string{"invoice", "invoice_period", "product", "project", "group_description", "category"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *InvoiceItemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.Amount
+}
+
+// Collect sends the metric values for each metric pertaining to invoice items
+// to the provided prometheus Metric channel.
+func (c *InvoiceItemCollector) Collect(ch chan<- prometheus.Metric) {
+	for i, amount := range c.dos.Snapshot().InvoiceItems() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Amount,
+			prometheus.GaugeValue,
+			amount,
+			i.invoice,
+			i.invoicePeriod,
+			i.product,
+			i.project,
+			i.groupDescription,
+			i.category,
+		)
+	}
+}
@@ -0,0 +1,249 @@
+package digitaloceanexporter
+
+import (
+	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceKubernetes, true, []string{resourceKubernetes}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewKubernetesCollector(dos)
+	})
+}
+
+// A KubernetesCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Kubernetes (DOKS) clusters and their node pools.
+type KubernetesCollector struct {
+	Info             *prometheus.Desc
+	UpgradeAvailable *prometheus.Desc
+	AutoUpgrade      *prometheus.Desc
+	SurgeUpgrade     *prometheus.Desc
+	NodePools        *prometheus.Desc
+	DesiredNodes     *prometheus.Desc
+	Nodes            *prometheus.Desc
+	AutoScale        *prometheus.Desc
+	MinNodes         *prometheus.Desc
+	MaxNodes         *prometheus.Desc
+	NodeInfo         *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that KubernetesCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &KubernetesCollector{}
+
+// NewKubernetesCollector creates a new KubernetesCollector which collects
+// metrics about the DOKS clusters in a DigitalOcean account.
+func NewKubernetesCollector(dos DigitalOceanSource) *KubernetesCollector {
+	labels := []string{"id"}
+	poolLabels := []string{"id", "node_pool"}
+
+	return &KubernetesCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_cluster", "info"),
+			"Information about a DOKS cluster, always 1.",
+			[]string{"id", "name", "region", "version", "status"},
+			nil,
+		),
+		UpgradeAvailable: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_cluster", "upgrade_available"),
+			"Whether a newer Kubernetes version is available for a DOKS cluster.",
+			labels,
+			nil,
+		),
+		AutoUpgrade: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_cluster", "auto_upgrade"),
+			"Whether a DOKS cluster is upgraded automatically.",
+			labels,
+			nil,
+		),
+		SurgeUpgrade: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_cluster", "surge_upgrade"),
+			"Whether a DOKS cluster is upgraded with surge nodes.",
+			labels,
+			nil,
+		),
+		NodePools: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_cluster", "node_pools"),
+			"Number of node pools of a DOKS cluster.",
+			labels,
+			nil,
+		),
+		DesiredNodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_node_pool", "desired_nodes"),
+			"Number of nodes a DOKS node pool should have.",
+			[]string{"id", "node_pool", "size"},
+			nil,
+		),
+		Nodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_node_pool", "nodes"),
+			"Number of nodes a DOKS node pool has.",
+			poolLabels,
+			nil,
+		),
+		AutoScale: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_node_pool", "auto_scale"),
+			"Whether a DOKS node pool is scaled automatically.",
+			poolLabels,
+			nil,
+		),
+		MinNodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_node_pool", "auto_scale_min_nodes"),
+			"Minimum number of nodes of an automatically scaled DOKS node pool.",
+			poolLabels,
+			nil,
+		),
+		MaxNodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_node_pool", "auto_scale_max_nodes"),
+			"Maximum number of nodes of an automatically scaled DOKS node pool.",
+			poolLabels,
+			nil,
+		),
+		NodeInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "kubernetes_node", "info"),
+			"Information about a node of a DOKS node pool, always 1.",
+			[]string{"id", "node_pool", "node", "droplet_id", "status"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *KubernetesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.UpgradeAvailable,
+		c.AutoUpgrade,
+		c.SurgeUpgrade,
+		c.NodePools,
+		c.DesiredNodes,
+		c.Nodes,
+		c.AutoScale,
+		c.MinNodes,
+		c.MaxNodes,
+		c.NodeInfo,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to DOKS clusters
+// to the provided prometheus Metric channel.
+func (c *KubernetesCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, k := range c.dos.Snapshot().KubernetesList() {
+		c.collectCluster(ch, k)
+	}
+}
+
+func (c *KubernetesCollector) collectCluster(ch chan<- prometheus.Metric, k KubernetesCluster) {
+	cluster := k.cluster
+
+	var status string
+	if cluster.Status != nil {
+		status = string(cluster.Status.State)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1,
+		cluster.ID,
+		cluster.Name,
+		cluster.RegionSlug,
+		cluster.VersionSlug,
+		status,
+	)
+
+	flags := []struct {
+		desc  *prometheus.Desc
+		value bool
+	}{
+		{c.UpgradeAvailable, len(k.upgrades) > 0},
+		{c.AutoUpgrade, cluster.AutoUpgrade},
+		{c.SurgeUpgrade, cluster.SurgeUpgrade},
+	}
+	for _, f := range flags {
+		ch <- prometheus.MustNewConstMetric(
+			f.desc,
+			prometheus.GaugeValue,
+			boolToFloat64(f.value),
+			cluster.ID,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.NodePools,
+		prometheus.GaugeValue,
+		float64(len(cluster.NodePools)),
+		cluster.ID,
+	)
+
+	for _, pool := range cluster.NodePools {
+		c.collectNodePool(ch, cluster.ID, pool)
+	}
+}
+
+func (c *KubernetesCollector) collectNodePool(ch chan<- prometheus.Metric, clusterID string, pool *godo.KubernetesNodePool) {
+	ch <- prometheus.MustNewConstMetric(
+		c.DesiredNodes,
+		prometheus.GaugeValue,
+		float64(pool.Count),
+		clusterID,
+		pool.Name,
+		pool.Size,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.Nodes,
+		prometheus.GaugeValue,
+		float64(len(pool.Nodes)),
+		clusterID,
+		pool.Name,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.AutoScale,
+		prometheus.GaugeValue,
+		boolToFloat64(pool.AutoScale),
+		clusterID,
+		pool.Name,
+	)
+
+	if pool.AutoScale {
+		ch <- prometheus.MustNewConstMetric(
+			c.MinNodes,
+			prometheus.GaugeValue,
+			float64(pool.MinNodes),
+			clusterID,
+			pool.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.MaxNodes,
+			prometheus.GaugeValue,
+			float64(pool.MaxNodes),
+			clusterID,
+			pool.Name,
+		)
+	}
+
+	for _, node := range pool.Nodes {
+		var status string
+		if node.Status != nil {
+			status = node.Status.State
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.NodeInfo,
+			prometheus.GaugeValue,
+			1,
+			clusterID,
+			pool.Name,
+			node.Name,
+			node.DropletID,
+			status,
+		)
+	}
+}
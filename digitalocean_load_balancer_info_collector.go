@@ -0,0 +1,258 @@
+package digitaloceanexporter
+
+import (
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	resources := []string{resourceLoadBalancers, resourceDroplets}
+	registerCollector("load_balancer_info", false, resources, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewLoadBalancerInfoCollector(dos)
+	})
+}
+
+// A LoadBalancerInfoCollector is a Prometheus collector for metrics regarding
+// the configuration of individual DigitalOcean Load Balancers.
+type LoadBalancerInfoCollector struct {
+	Info                       *prometheus.Desc
+	SizeUnits                  *prometheus.Desc
+	BackendDroplets            *prometheus.Desc
+	BackendTagEmpty            *prometheus.Desc
+	ForwardingRules            *prometheus.Desc
+	RedirectHTTPToHTTPS        *prometheus.Desc
+	HealthCheckInfo            *prometheus.Desc
+	HealthCheckInterval        *prometheus.Desc
+	HealthCheckResponseTimeout *prometheus.Desc
+	HealthCheckHealthy         *prometheus.Desc
+	HealthCheckUnhealthy       *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that LoadBalancerInfoCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &LoadBalancerInfoCollector{}
+
+// NewLoadBalancerInfoCollector creates a new LoadBalancerInfoCollector which
+// collects metrics about each Load Balancer in a DigitalOcean account.
+func NewLoadBalancerInfoCollector(dos DigitalOceanSource) *LoadBalancerInfoCollector {
+	labels := []string{"id"}
+
+	return &LoadBalancerInfoCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "info"),
+			"Information about a Load Balancer, always 1.",
+			[]string{"id", "name", "region", "status", "size", "algorithm", "sticky_sessions", "tag"},
+			nil,
+		),
+		SizeUnits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "size_units"),
+			"Number of nodes of a Load Balancer.",
+			labels,
+			nil,
+		),
+		BackendDroplets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "backend_droplets"),
+			"Number of Droplets behind a Load Balancer.",
+			labels,
+			nil,
+		),
+		BackendTagEmpty: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "backend_tag_empty"),
+			"Whether the tag selecting the Droplets behind a Load Balancer matches no Droplet.",
+			[]string{"id", "tag"},
+			nil,
+		),
+		ForwardingRules: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "forwarding_rules"),
+			"Number of forwarding rules of a Load Balancer by entry protocol.",
+			[]string{"id", "entry_protocol"},
+			nil,
+		),
+		RedirectHTTPToHTTPS: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "redirect_http_to_https"),
+			"Whether a Load Balancer redirects HTTP requests to HTTPS.",
+			labels,
+			nil,
+		),
+		HealthCheckInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "health_check_info"),
+			"Health check of a Load Balancer, always 1.",
+			[]string{"id", "protocol", "port", "path"},
+			nil,
+		),
+		HealthCheckInterval: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "health_check_interval_seconds"),
+			"Time between health checks of a Load Balancer in seconds.",
+			labels,
+			nil,
+		),
+		HealthCheckResponseTimeout: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "health_check_response_timeout_seconds"),
+			"Time a Load Balancer waits for a response to a health check in seconds.",
+			labels,
+			nil,
+		),
+		HealthCheckHealthy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "health_check_healthy_threshold"),
+			"Number of passed health checks after which a Droplet is marked healthy.",
+			labels,
+			nil,
+		),
+		HealthCheckUnhealthy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancer", "health_check_unhealthy_threshold"),
+			"Number of failed health checks after which a Droplet is marked unhealthy.",
+			labels,
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *LoadBalancerInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.SizeUnits,
+		c.BackendDroplets,
+		c.BackendTagEmpty,
+		c.ForwardingRules,
+		c.RedirectHTTPToHTTPS,
+		c.HealthCheckInfo,
+		c.HealthCheckInterval,
+		c.HealthCheckResponseTimeout,
+		c.HealthCheckHealthy,
+		c.HealthCheckUnhealthy,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to individual
+// Load Balancers to the provided prometheus Metric channel.
+func (c *LoadBalancerInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	// The Droplets behind a Load Balancer selecting them by tag are counted
+	// from the Droplets of the Snapshot once they are known.
+	var tagged map[string]int
+	if s.available(resourceDroplets) {
+		tagged = make(map[string]int)
+		for _, d := range s.DropletList() {
+			for _, tag := range d.Tags {
+				tagged[tag]++
+			}
+		}
+	}
+
+	for _, lb := range s.LoadBalancerList() {
+		c.collectLoadBalancer(ch, lb, tagged)
+	}
+}
+
+func (c *LoadBalancerInfoCollector) collectLoadBalancer(ch chan<- prometheus.Metric, lb godo.LoadBalancer, tagged map[string]int) {
+	var region, stickySessions string
+	if lb.Region != nil {
+		region = lb.Region.Slug
+	}
+	if lb.StickySessions != nil {
+		stickySessions = lb.StickySessions.Type
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1,
+		lb.ID,
+		lb.Name,
+		region,
+		lb.Status,
+		lb.SizeSlug,
+		lb.Algorithm,
+		stickySessions,
+		lb.Tag,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.SizeUnits,
+		prometheus.GaugeValue,
+		float64(loadBalancerNodes(lb)),
+		lb.ID,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.RedirectHTTPToHTTPS,
+		prometheus.GaugeValue,
+		boolToFloat64(lb.RedirectHttpToHttps),
+		lb.ID,
+	)
+
+	backends := len(lb.DropletIDs)
+	if lb.Tag != "" && tagged != nil {
+		backends = tagged[lb.Tag]
+		ch <- prometheus.MustNewConstMetric(
+			c.BackendTagEmpty,
+			prometheus.GaugeValue,
+			boolToFloat64(backends == 0),
+			lb.ID,
+			lb.Tag,
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.BackendDroplets,
+		prometheus.GaugeValue,
+		float64(backends),
+		lb.ID,
+	)
+
+	rules := make(map[string]int)
+	for _, rule := range lb.ForwardingRules {
+		rules[rule.EntryProtocol]++
+	}
+	for protocol, count := range rules {
+		ch <- prometheus.MustNewConstMetric(
+			c.ForwardingRules,
+			prometheus.GaugeValue,
+			float64(count),
+			lb.ID,
+			protocol,
+		)
+	}
+
+	hc := lb.HealthCheck
+	if hc == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.HealthCheckInfo,
+		prometheus.GaugeValue,
+		1,
+		lb.ID,
+		hc.Protocol,
+		strconv.Itoa(hc.Port),
+		hc.Path,
+	)
+
+	thresholds := []struct {
+		desc  *prometheus.Desc
+		value int
+	}{
+		{c.HealthCheckInterval, hc.CheckIntervalSeconds},
+		{c.HealthCheckResponseTimeout, hc.ResponseTimeoutSeconds},
+		{c.HealthCheckHealthy, hc.HealthyThreshold},
+		{c.HealthCheckUnhealthy, hc.UnhealthyThreshold},
+	}
+	for _, t := range thresholds {
+		ch <- prometheus.MustNewConstMetric(
+			t.desc,
+			prometheus.GaugeValue,
+			float64(t.value),
+			lb.ID,
+		)
+	}
+}
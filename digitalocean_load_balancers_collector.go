@@ -0,0 +1,90 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceLoadBalancers, true, []string{resourceLoadBalancers}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewLoadBalancerCollector(dos)
+	})
+}
+
+// A LoadBalancerCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Load Balancers.
+type LoadBalancerCollector struct {
+	LoadBalancers *prometheus.Desc
+	CostHourly    *prometheus.Desc
+	CostMonthly   *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that LoadBalancerCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &LoadBalancerCollector{}
+
+// NewLoadBalancerCollector creates a new LoadBalancerCollector which collects
+// metrics about the Load Balancers in a DigitalOcean account.
+func NewLoadBalancerCollector(dos DigitalOceanSource) *LoadBalancerCollector {
+	return &LoadBalancerCollector{
+		LoadBalancers: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancers", "count"),
+			"Number of Load Balancers by region and status.",
+			[]string{"region", "status"},
+			nil,
+		),
+		CostHourly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancers", "cost_hourly_dollars"),
+			"Estimated hourly cost of Load Balancers by region in US dollars.",
+			[]string{"region"},
+			nil,
+		),
+		CostMonthly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "load_balancers", "cost_monthly_dollars"),
+			"Estimated monthly cost of Load Balancers by region in US dollars.",
+			[]string{"region"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *LoadBalancerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.LoadBalancers
+	ch <- c.CostHourly
+	ch <- c.CostMonthly
+}
+
+// Collect sends the metric values for each metric pertaining to Load
+// Balancers to the provided prometheus Metric channel.
+func (c *LoadBalancerCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	for lb, count := range s.LoadBalancers() {
+		ch <- prometheus.MustNewConstMetric(
+			c.LoadBalancers,
+			prometheus.GaugeValue,
+			float64(count),
+			lb.region,
+			lb.status,
+		)
+	}
+
+	for region, cost := range s.LoadBalancerCosts() {
+		ch <- prometheus.MustNewConstMetric(
+			c.CostHourly,
+			prometheus.GaugeValue,
+			cost.hourly,
+			region,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.CostMonthly,
+			prometheus.GaugeValue,
+			cost.monthly,
+			region,
+		)
+	}
+}
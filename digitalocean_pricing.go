@@ -0,0 +1,103 @@
+package digitaloceanexporter
+
+import (
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/digitalocean/godo"
+)
+
+// The DigitalOcean API reports the price of Droplet sizes only. The cost of
+// other resources is estimated from the list prices below, in US dollars per
+// month.
+const (
+	// hoursPerMonth is the number of hours after which DigitalOcean stops
+	// billing a resource for the rest of the month.
+	hoursPerMonth = 672
+
+	volumePricePerGiB     = 0.10
+	snapshotPricePerGiB   = 0.06
+	loadBalancerNodePrice = 12.00
+	floatingIPPrice       = 5.00
+)
+
+// loadBalancerSizeUnits maps the legacy Load Balancer size slugs to the
+// number of nodes they are billed as.
+var loadBalancerSizeUnits = map[string]uint32{
+	"lb-small":  1,
+	"lb-medium": 3,
+	"lb-large":  6,
+}
+
+// A Cost is the price of one or more resources in US dollars.
+type Cost struct {
+	hourly  float64
+	monthly float64
+}
+
+// add returns the sum of two Costs.
+func (c Cost) add(o Cost) Cost {
+	return Cost{
+		hourly:  c.hourly + o.hourly,
+		monthly: c.monthly + o.monthly,
+	}
+}
+
+// monthlyCost returns the Cost of a resource billed at the given monthly
+// price.
+func monthlyCost(monthly float64) Cost {
+	return Cost{
+		hourly:  monthly / hoursPerMonth,
+		monthly: monthly,
+	}
+}
+
+// volumeCost estimates the Cost of a Block Storage Volume from its size.
+func volumeCost(v godo.Volume) Cost {
+	return monthlyCost(float64(v.SizeGigaBytes) * volumePricePerGiB)
+}
+
+// snapshotCost estimates the Cost of storing a Snapshot in a single region
+// from its size.
+func snapshotCost(s godo.Snapshot) Cost {
+	return monthlyCost(s.SizeGigaBytes * snapshotPricePerGiB)
+}
+
+// loadBalancerNodes returns the number of nodes of a Load Balancer, which
+// is only reported as SizeUnit for Load Balancers not using a legacy size.
+func loadBalancerNodes(lb godo.LoadBalancer) uint32 {
+	units := lb.SizeUnit
+	if units == 0 {
+		units = loadBalancerSizeUnits[lb.SizeSlug]
+	}
+	if units == 0 {
+		units = 1
+	}
+	return units
+}
+
+// loadBalancerCost estimates the Cost of a Load Balancer from its number of
+// nodes.
+func loadBalancerCost(lb godo.LoadBalancer) Cost {
+	return monthlyCost(float64(loadBalancerNodes(lb)) * loadBalancerNodePrice)
+}
+
+// floatingIPCost estimates the Cost of a Floating IP, which is only billed
+// while it is not assigned to a Droplet.
+func floatingIPCost(fip godo.FloatingIP) Cost {
+	if fip.Droplet != nil {
+		return Cost{}
+	}
+	return monthlyCost(floatingIPPrice)
+}
+
+// parseAmount parses an amount in US dollars as returned by the DigitalOcean
+// API, which reports amounts as strings.
+func parseAmount(amount string) (float64, bool) {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		logrus.WithField("amount", amount).WithError(err).Debugln("Cannot parse amount")
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,260 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceRegistry, true, []string{resourceRegistry}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewRegistryCollector(dos)
+	})
+}
+
+// A RegistryCollector is a Prometheus collector for metrics regarding the
+// DigitalOcean Container Registry of an account.
+type RegistryCollector struct {
+	Info                     *prometheus.Desc
+	StorageUsage             *prometheus.Desc
+	StorageUsageUpdated      *prometheus.Desc
+	IncludedStorage          *prometheus.Desc
+	StorageUtilization       *prometheus.Desc
+	Repositories             *prometheus.Desc
+	RepositoryTags           *prometheus.Desc
+	RepositoryManifests      *prometheus.Desc
+	RepositoryManifestSize   *prometheus.Desc
+	RepositoryUpdated        *prometheus.Desc
+	GarbageCollectionStatus  *prometheus.Desc
+	GarbageCollectionUpdated *prometheus.Desc
+	GarbageCollectionFreed   *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that RegistryCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &RegistryCollector{}
+
+// NewRegistryCollector creates a new RegistryCollector which collects metrics
+// about the container registry of a DigitalOcean account.
+func NewRegistryCollector(dos DigitalOceanSource) *RegistryCollector {
+	labels := []string{"registry"}
+	repositoryLabels := []string{"registry", "repository"}
+
+	return &RegistryCollector{
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "info"),
+			"Information about the container registry, always 1.",
+			[]string{"registry", "region", "tier"},
+			nil,
+		),
+		StorageUsage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "storage_usage_bytes"),
+			"Storage used by the container registry in bytes.",
+			labels,
+			nil,
+		),
+		StorageUsageUpdated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "storage_usage_updated_timestamp_seconds"),
+			"Time at which the storage usage of the container registry was last updated, in seconds since the Unix epoch.",
+			labels,
+			nil,
+		),
+		IncludedStorage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "included_storage_bytes"),
+			"Storage included in the subscription tier of the container registry in bytes.",
+			labels,
+			nil,
+		),
+		StorageUtilization: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "storage_utilization_ratio"),
+			"Ratio of the storage used by the container registry to the storage included in its subscription tier.",
+			labels,
+			nil,
+		),
+		Repositories: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "repositories"),
+			"Number of repositories of the container registry.",
+			labels,
+			nil,
+		),
+		RepositoryTags: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "repository_tags"),
+			"Number of tags of a repository.",
+			repositoryLabels,
+			nil,
+		),
+		RepositoryManifests: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "repository_manifests"),
+			"Number of manifests of a repository.",
+			repositoryLabels,
+			nil,
+		),
+		RepositoryManifestSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "repository_manifest_size_bytes"),
+			"Total size of the manifests of a repository in bytes.",
+			repositoryLabels,
+			nil,
+		),
+		RepositoryUpdated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "repository_updated_timestamp_seconds"),
+			"Time at which a repository was last pushed to, in seconds since the Unix epoch.",
+			repositoryLabels,
+			nil,
+		),
+		GarbageCollectionStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "garbage_collection_status"),
+			"Status of the most recent garbage collection of the container registry, always 1.",
+			[]string{"registry", "status"},
+			nil,
+		),
+		GarbageCollectionUpdated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "garbage_collection_updated_timestamp_seconds"),
+			"Time at which the most recent garbage collection of the container registry was last updated, in seconds since the Unix epoch.",
+			labels,
+			nil,
+		),
+		GarbageCollectionFreed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "registry", "garbage_collection_freed_bytes"),
+			"Storage freed by the most recent garbage collection of the container registry in bytes.",
+			labels,
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *RegistryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Info,
+		c.StorageUsage,
+		c.StorageUsageUpdated,
+		c.IncludedStorage,
+		c.StorageUtilization,
+		c.Repositories,
+		c.RepositoryTags,
+		c.RepositoryManifests,
+		c.RepositoryManifestSize,
+		c.RepositoryUpdated,
+		c.GarbageCollectionStatus,
+		c.GarbageCollectionUpdated,
+		c.GarbageCollectionFreed,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to the container
+// registry to the provided prometheus Metric channel.
+func (c *RegistryCollector) Collect(ch chan<- prometheus.Metric) {
+	r := c.dos.Snapshot().Registry()
+	if r == nil {
+		return
+	}
+	name := r.registry.Name
+
+	var tier string
+	var included uint64
+	if r.subscription != nil && r.subscription.Tier != nil {
+		tier = r.subscription.Tier.Slug
+		included = r.subscription.Tier.IncludedStorageBytes
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1,
+		name,
+		r.registry.Region,
+		tier,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.StorageUsage,
+		prometheus.GaugeValue,
+		float64(r.registry.StorageUsageBytes),
+		name,
+	)
+	if !r.registry.StorageUsageBytesUpdatedAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			c.StorageUsageUpdated,
+			prometheus.GaugeValue,
+			float64(r.registry.StorageUsageBytesUpdatedAt.Unix()),
+			name,
+		)
+	}
+	if included > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.IncludedStorage,
+			prometheus.GaugeValue,
+			float64(included),
+			name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.StorageUtilization,
+			prometheus.GaugeValue,
+			float64(r.registry.StorageUsageBytes)/float64(included),
+			name,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Repositories,
+		prometheus.GaugeValue,
+		float64(len(r.repositories)),
+		name,
+	)
+	for _, repo := range r.repositories {
+		counts := []struct {
+			desc  *prometheus.Desc
+			value float64
+		}{
+			{c.RepositoryTags, float64(repo.repository.TagCount)},
+			{c.RepositoryManifests, float64(repo.manifests)},
+			{c.RepositoryManifestSize, float64(repo.manifestBytes)},
+		}
+		for _, cnt := range counts {
+			ch <- prometheus.MustNewConstMetric(
+				cnt.desc,
+				prometheus.GaugeValue,
+				cnt.value,
+				name,
+				repo.repository.Name,
+			)
+		}
+
+		if !repo.updatedAt.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.RepositoryUpdated,
+				prometheus.GaugeValue,
+				float64(repo.updatedAt.Unix()),
+				name,
+				repo.repository.Name,
+			)
+		}
+	}
+
+	if gc := r.garbageCollection; gc != nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.GarbageCollectionStatus,
+			prometheus.GaugeValue,
+			1,
+			name,
+			gc.Status,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.GarbageCollectionUpdated,
+			prometheus.GaugeValue,
+			float64(gc.UpdatedAt.Unix()),
+			name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.GarbageCollectionFreed,
+			prometheus.GaugeValue,
+			float64(gc.FreedBytes),
+			name,
+		)
+	}
+}
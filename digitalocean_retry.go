@@ -0,0 +1,131 @@
+package digitaloceanexporter
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/digitalocean/godo"
+)
+
+const (
+	DefaultMaxRetries int = 3
+
+	defaultRetryBaseDelay = 1 * time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// requestWithRetry calls list for a single page, retrying transient errors
+// with a jittered exponential backoff until the request succeeds, the
+// maximum number of retries is reached or ctx is done.
+func (b *DigitalOceanBuffer) requestWithRetry(ctx context.Context, resource string, pageOpt *godo.ListOptions, list func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error)) (*godo.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := b.request(ctx, resource, pageOpt, list)
+		if err == nil || attempt >= b.maxRetries || !retryable(err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := b.retryDelay(attempt, resp, err)
+		logrus.WithFields(logrus.Fields{
+			"refreshID": refreshIDFrom(ctx),
+			"page":      pageOpt.Page,
+			"attempt":   attempt + 1,
+			"delay":     delay.String(),
+		}).WithError(err).Warningf("Retrying request for %s", resource)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return resp, err
+		}
+	}
+}
+
+// request calls list for a single page once a slot for concurrent requests
+// is free, and records the rate limit reported in the response.
+func (b *DigitalOceanBuffer) request(ctx context.Context, resource string, pageOpt *godo.ListOptions, list func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error)) (*godo.Response, error) {
+	if err := b.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer b.release()
+
+	reqCtx, cancel := b.requestContext(ctx)
+	defer cancel()
+
+	if info := refreshInfoFrom(ctx); info != nil {
+		atomic.AddInt64(&info.requests, 1)
+	}
+
+	count, resp, err := list(reqCtx, pageOpt)
+	b.logSearchRequest(ctx, resource, pageOpt, count, err)
+
+	if resp != nil {
+		b.observeRate(resp.Rate)
+	}
+
+	return resp, err
+}
+
+// observeRate records the rate limit reported by the DigitalOcean API.
+func (b *DigitalOceanBuffer) observeRate(rate godo.Rate) {
+	if rate.Limit == 0 {
+		return
+	}
+
+	b.rateMu.Lock()
+	b.rate = rate
+	b.rateMu.Unlock()
+}
+
+// lastRate retrieves the most recently observed rate limit.
+func (b *DigitalOceanBuffer) lastRate() godo.Rate {
+	b.rateMu.Lock()
+	defer b.rateMu.Unlock()
+
+	return b.rate
+}
+
+// retryDelay computes how long to wait before retrying a failed request. The
+// Retry-After header and the rate limit reset time are honoured when the
+// request was rate limited; otherwise the delay grows exponentially with
+// each attempt and is jittered so concurrent requests do not retry in step.
+func (b *DigitalOceanBuffer) retryDelay(attempt int, resp *godo.Response, err error) time.Duration {
+	if errResp, ok := err.(*godo.ErrorResponse); ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(errResp.Response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+
+		if resp != nil && resp.Rate.Remaining == 0 && !resp.Rate.Reset.IsZero() {
+			if delay := resp.Rate.Reset.Sub(time.Now()); delay > 0 {
+				return delay
+			}
+		}
+	}
+
+	delay := b.retryMaxDelay
+	if attempt < 16 {
+		if d := b.retryBaseDelay << uint(attempt); d < delay {
+			delay = d
+		}
+	}
+
+	if delay <= 1 {
+		return delay
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+// retryable reports whether a failed request may succeed if retried.
+func retryable(err error) bool {
+	switch classifyError(err) {
+	case errorClassRateLimit, errorClassServerError, errorClassTimeout, errorClassNetwork:
+		return true
+	default:
+		return false
+	}
+}
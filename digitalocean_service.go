@@ -3,8 +3,12 @@ package digitaloceanexporter
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Sirupsen/logrus"
@@ -13,7 +17,156 @@ import (
 )
 
 const (
-	DefaultRefreshInterval int = 60
+	DefaultRefreshInterval int     = 60
+	DefaultConcurrency     int     = 4
+	DefaultRateLimitBudget float64 = 0.5
+
+	// DefaultBillingRefreshInterval is the default interval (in seconds)
+	// between refreshes of billing data, which changes rarely.
+	DefaultBillingRefreshInterval int = 3600
+
+	// DefaultRegistryRefreshInterval is the default interval (in seconds)
+	// between refreshes of the container registry, which takes a request
+	// per repository.
+	DefaultRegistryRefreshInterval int = 600
+
+	// DefaultDomainsRefreshInterval is the default interval (in seconds)
+	// between refreshes of domains, which takes a request per domain.
+	DefaultDomainsRefreshInterval int = 600
+
+	// DefaultDatabasesRefreshInterval is the default interval (in seconds)
+	// between refreshes of database clusters, which takes up to three
+	// requests per cluster.
+	DefaultDatabasesRefreshInterval int = 300
+
+	// DefaultAppsRefreshInterval is the default interval (in seconds)
+	// between refreshes of App Platform apps, which takes a request per app.
+	DefaultAppsRefreshInterval int = 300
+
+	// recentInvoices is the number of most recent invoices exported.
+	recentInvoices = 12
+
+	// recentDeployments is the number of most recent deployments of each
+	// App Platform app which are counted.
+	recentDeployments = 20
+)
+
+// DefaultResourceRefreshIntervals returns the default refresh interval (in
+// seconds) of the resource types which are not refreshed at RefreshInterval
+// unless configured otherwise, keyed by resource type.
+func DefaultResourceRefreshIntervals() map[string]int {
+	return map[string]int{
+		resourceApps:         DefaultAppsRefreshInterval,
+		resourceBilling:      DefaultBillingRefreshInterval,
+		resourceDatabases:    DefaultDatabasesRefreshInterval,
+		resourceDomains:      DefaultDomainsRefreshInterval,
+		resourceInvoiceItems: DefaultBillingRefreshInterval,
+		resourceRegistry:     DefaultRegistryRefreshInterval,
+	}
+}
+
+// BufferConfig holds the settings which control how a DigitalOceanBuffer
+// refreshes and serves its data.
+type BufferConfig struct {
+	// RefreshInterval is the time between subsequent refreshes.
+	RefreshInterval time.Duration
+
+	// MaxStaleness is the age after which data retained from the last
+	// successful refresh of a resource type is no longer served. Zero
+	// serves retained data indefinitely.
+	MaxStaleness time.Duration
+
+	// Concurrency is the maximum number of requests made against the
+	// DigitalOcean API at the same time.
+	Concurrency int
+
+	// RefreshTimeout is the deadline for a whole refresh, after which any
+	// outstanding requests are aborted. Zero means no deadline.
+	RefreshTimeout time.Duration
+
+	// RequestTimeout is the deadline for a single request against the
+	// DigitalOcean API. Zero means no deadline.
+	RequestTimeout time.Duration
+
+	// MaxRetries is the number of times a request which failed with a
+	// transient error is retried.
+	MaxRetries int
+
+	// AdaptiveRefresh stretches the time between refreshes beyond
+	// RefreshInterval when refreshing at RefreshInterval would use more
+	// than RateLimitBudget of the token's hourly rate limit.
+	AdaptiveRefresh bool
+
+	// RateLimitBudget is the fraction of the hourly rate limit that
+	// refreshes may use when AdaptiveRefresh is enabled.
+	RateLimitBudget float64
+
+	// ResourceRefreshIntervals overrides RefreshInterval for individual
+	// resource types, keyed by resource type. Resource types sharing an
+	// interval are refreshed together.
+	ResourceRefreshIntervals map[string]time.Duration
+
+	// DropletTags controls how the tags of Droplets are turned into labels.
+	DropletTags TagConfig
+
+	// Resources limits refreshes to the named resource types. All resource
+	// types are refreshed when it is nil.
+	Resources []string
+}
+
+// Names of the resource types refreshed from the DigitalOcean API, used to
+// label per-resource refresh metrics.
+const (
+	resourceAccount       = "account"
+	resourceApps          = "apps"
+	resourceBilling       = "billing"
+	resourceCertificates  = "certificates"
+	resourceDatabases     = "databases"
+	resourceDomains       = "domains"
+	resourceDroplets      = "droplets"
+	resourceFirewalls     = "firewalls"
+	resourceFloatingIPs   = "floating_ips"
+	resourceInvoiceItems  = "invoice_items"
+	resourceKubernetes    = "kubernetes"
+	resourceLoadBalancers = "load_balancers"
+	resourceRegistry      = "registry"
+	resourceSnapshots     = "snapshots"
+	resourceTags          = "tags"
+	resourceVolumes       = "volumes"
+)
+
+// Resources returns the names of the resource types which are refreshed from
+// the DigitalOcean API.
+func Resources() []string {
+	return []string{
+		resourceAccount,
+		resourceApps,
+		resourceBilling,
+		resourceCertificates,
+		resourceDatabases,
+		resourceDomains,
+		resourceDroplets,
+		resourceFirewalls,
+		resourceFloatingIPs,
+		resourceInvoiceItems,
+		resourceKubernetes,
+		resourceLoadBalancers,
+		resourceRegistry,
+		resourceSnapshots,
+		resourceTags,
+		resourceVolumes,
+	}
+}
+
+// Classes of errors returned while querying the DigitalOcean API.
+const (
+	errorClassAuth        = "auth"
+	errorClassRateLimit   = "rate_limit"
+	errorClassServerError = "server_error"
+	errorClassClientError = "client_error"
+	errorClassTimeout     = "timeout"
+	errorClassNetwork     = "network"
+	errorClassOther       = "other"
 )
 
 // DigitalOceanService is a wrapper around godo.Client.
@@ -23,12 +176,49 @@ type DigitalOceanService struct {
 
 // DropletCounter is a struct holding information about a Droplet.
 type DropletCounter struct {
-	status        string
-	region        string
-	size          string
-	price_hourly  float64
-	price_monthly float64
-	tags          string
+	status     string
+	region     string
+	size       string
+	doksWorker string
+	tags       string
+	promoted   string
+}
+
+// DropletCostCounter is a struct holding information about the Droplets
+// whose cost is summed.
+type DropletCostCounter struct {
+	region   string
+	size     string
+	tags     string
+	promoted string
+}
+
+// DropletFeatureCounter is a struct holding information about whether a
+// Droplet has a feature enabled.
+type DropletFeatureCounter struct {
+	feature  string
+	enabled  bool
+	region   string
+	tags     string
+	promoted string
+}
+
+// dropletFeatures lists the Droplet features whose coverage is counted.
+var dropletFeatures = []string{"backups", "ipv6", "private_networking", "monitoring"}
+
+// DomainRecordCounter is a struct holding information about the records of a
+// domain.
+type DomainRecordCounter struct {
+	domain     string
+	recordType string
+}
+
+// AddressRecord is a struct holding information about an A or AAAA record.
+type AddressRecord struct {
+	domain     string
+	name       string
+	recordType string
+	data       string
 }
 
 // FlipCounter is a struct holding information about a Floating IP.
@@ -49,6 +239,24 @@ type TagCounter struct {
 	resourceType string
 }
 
+// SnapshotCounter is a struct holding information about a Snapshot or a
+// custom image.
+type SnapshotCounter struct {
+	region       string
+	resourceType string
+}
+
+// SnapshotSourceCounter is a struct holding information about the resource a
+// Snapshot was taken from.
+type SnapshotSourceCounter struct {
+	resourceID   string
+	resourceType string
+}
+
+// imageResourceType is the resource type under which custom images are
+// counted alongside Snapshots.
+const imageResourceType = "image"
+
 // VolumeCounter is a struct holding information about a Block Storage Volume.
 type VolumeCounter struct {
 	status string
@@ -56,6 +264,46 @@ type VolumeCounter struct {
 	size   string
 }
 
+// InvoiceItemCounter is a struct holding information about the items of an
+// invoice whose amounts are summed.
+type InvoiceItemCounter struct {
+	invoice          string
+	invoicePeriod    string
+	product          string
+	project          string
+	groupDescription string
+	category         string
+}
+
+// Invoices whose items are exported.
+const (
+	invoiceClosed  = "closed"
+	invoicePreview = "preview"
+)
+
+// RefreshStatus is a struct holding the outcome of the most recent refresh
+// of a resource type.
+type RefreshStatus struct {
+	success     bool
+	lastSuccess time.Time
+	duration    time.Duration
+	requests    int
+	interval    time.Duration
+}
+
+// stale reports whether the most recent refresh failed and the data from an
+// earlier successful refresh is being served in its place.
+func (s RefreshStatus) stale() bool {
+	return !s.success && !s.lastSuccess.IsZero()
+}
+
+// RefreshErrorCounter is a struct holding information about a failed refresh
+// of a resource type.
+type RefreshErrorCounter struct {
+	resource string
+	class    string
+}
+
 func newPageOpt() *godo.ListOptions {
 	return &godo.ListOptions{
 		Page:    1,
@@ -63,34 +311,63 @@ func newPageOpt() *godo.ListOptions {
 	}
 }
 
+// Snapshot retrieves a consistent view of the resources in the DigitalOcean
+// account as of the most recent refresh.
+func (s *DigitalOceanService) Snapshot() *Snapshot {
+	return s.Buffer.Snapshot()
+}
+
 // Droplets retrieves a count of Droplets grouped by status, size, and region.
 func (s *DigitalOceanService) Droplets() map[DropletCounter]int {
-	return s.Buffer.Droplets
+	return s.Snapshot().Droplets()
 }
 
 // FloatingIPs retrieves a count of Floating IPs grouped by status and region.
 func (s *DigitalOceanService) FloatingIPs() map[FlipCounter]int {
-	return s.Buffer.FloatingIPs
+	return s.Snapshot().FloatingIPs()
 }
 
 // LoadBalancers retrieves a count of Load Balancers grouped by status and region.
 func (s *DigitalOceanService) LoadBalancers() map[LoadBalancerCounter]int {
-	return s.Buffer.LoadBalancers
+	return s.Snapshot().LoadBalancers()
 }
 
 // Tags retrieves a count of Tags grouped by name and resource type.
 func (s *DigitalOceanService) Tags() map[TagCounter]int {
-	return s.Buffer.Tags
+	return s.Snapshot().Tags()
 }
 
 // Volumes retrieves a count of Volumes grouped by status, size, and region.
 func (s *DigitalOceanService) Volumes() map[VolumeCounter]int {
-	return s.Buffer.Volumes
+	return s.Snapshot().Volumes()
 }
 
 // QueryDuration reports the time elapsed while querying the DigitalOcean API.
 func (s *DigitalOceanService) QueryDuration() time.Duration {
-	return s.Buffer.QueryDuration
+	return s.Snapshot().QueryDuration()
+}
+
+// RefreshStatus retrieves the outcome of the most recent refresh of each
+// resource type.
+func (s *DigitalOceanService) RefreshStatus() map[string]RefreshStatus {
+	return s.Snapshot().RefreshStatus()
+}
+
+// RefreshErrors retrieves a count of failed refreshes grouped by resource type
+// and error class.
+func (s *DigitalOceanService) RefreshErrors() map[RefreshErrorCounter]int {
+	return s.Snapshot().RefreshErrors()
+}
+
+// RefreshTimeouts retrieves a count of refreshes aborted by a timeout grouped
+// by resource type.
+func (s *DigitalOceanService) RefreshTimeouts() map[string]int {
+	return s.Snapshot().RefreshTimeouts()
+}
+
+// Rate retrieves the most recently observed DigitalOcean API rate limit.
+func (s *DigitalOceanService) Rate() godo.Rate {
+	return s.Snapshot().Rate()
 }
 
 func NewDigitalOceanService(buffer *DigitalOceanBuffer) *DigitalOceanService {
@@ -100,109 +377,781 @@ func NewDigitalOceanService(buffer *DigitalOceanBuffer) *DigitalOceanService {
 }
 
 type DigitalOceanBuffer struct {
-	client          *godo.Client
-	refreshInterval time.Duration
-	refreshID       uuid.UUID
+	client           *godo.Client
+	refreshInterval  time.Duration
+	resourceInterval map[string]time.Duration
+	resources        []string
+	dropletTags      TagConfig
+	maxStaleness     time.Duration
+	refreshTimeout   time.Duration
+	requestTimeout   time.Duration
+	requests         chan struct{}
+	maxRetries       int
+	retryBaseDelay   time.Duration
+	retryMaxDelay    time.Duration
+	adaptive         bool
+	rateLimitBudget  float64
+
+	rateMu sync.Mutex
+	rate   godo.Rate
+
+	// demand holds the requests per hour each group of resource types
+	// makes at its configured interval, keyed by the group's first
+	// resource type.
+	demandMu sync.Mutex
+	demand   map[string]float64
+
+	mu       sync.RWMutex
+	snapshot *Snapshot
+}
 
-	Droplets      map[DropletCounter]int
-	FloatingIPs   map[FlipCounter]int
-	LoadBalancers map[LoadBalancerCounter]int
-	Tags          map[TagCounter]int
-	Volumes       map[VolumeCounter]int
+// Snapshot retrieves the Snapshot built by the most recent refresh.
+func (b *DigitalOceanBuffer) Snapshot() *Snapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	QueryDuration time.Duration
+	return b.snapshot
 }
 
-func (b *DigitalOceanBuffer) listDroplets() ([]godo.Droplet, error) {
-	ctx := context.TODO()
-	dropletList := []godo.Droplet{}
+// preparers returns the functions which refresh the data for each resource
+// type, keyed by resource type. Each function returns another which applies
+// the refreshed data to a Snapshot.
+func (b *DigitalOceanBuffer) preparers() map[string]func(context.Context) (func(*Snapshot), error) {
+	return map[string]func(context.Context) (func(*Snapshot), error){
+		resourceAccount:       b.prepareAccount,
+		resourceApps:          b.prepareApps,
+		resourceBilling:       b.prepareBilling,
+		resourceCertificates:  b.prepareCertificates,
+		resourceDatabases:     b.prepareDatabases,
+		resourceDomains:       b.prepareDomains,
+		resourceDroplets:      b.prepareDroplets,
+		resourceFirewalls:     b.prepareFirewalls,
+		resourceFloatingIPs:   b.prepareFloatingIPs,
+		resourceInvoiceItems:  b.prepareInvoiceItems,
+		resourceKubernetes:    b.prepareKubernetes,
+		resourceLoadBalancers: b.prepareLoadBalancers,
+		resourceRegistry:      b.prepareRegistry,
+		resourceSnapshots:     b.prepareSnapshots,
+		resourceTags:          b.prepareTags,
+		resourceVolumes:       b.prepareVolumes,
+	}
+}
+
+// A refreshInfo identifies the refresh a request belongs to and counts the
+// requests it makes. It is carried in the context of the refresh.
+type refreshInfo struct {
+	// requests is accessed atomically and kept first so that it is
+	// 64-bit aligned on 32-bit platforms.
+	requests int64
+	id       uuid.UUID
+}
+
+type refreshInfoKey struct{}
+
+func withRefreshInfo(ctx context.Context, info *refreshInfo) context.Context {
+	return context.WithValue(ctx, refreshInfoKey{}, info)
+}
+
+func refreshInfoFrom(ctx context.Context) *refreshInfo {
+	info, _ := ctx.Value(refreshInfoKey{}).(*refreshInfo)
+	return info
+}
+
+// refreshIDFrom retrieves the ID of the refresh carried in ctx, if any.
+func refreshIDFrom(ctx context.Context) uuid.UUID {
+	if info := refreshInfoFrom(ctx); info != nil {
+		return info.id
+	}
+	return uuid.Nil
+}
+
+// A refreshResult is the outcome of refreshing a single resource type.
+type refreshResult struct {
+	resource string
+	apply    func(*Snapshot)
+	duration time.Duration
+	requests int
+	err      error
+}
+
+// update refreshes each of the given resource types in parallel and then
+// merges the results into a copy of the current Snapshot, which is atomically
+// swapped in. Resource types refreshed by other, concurrent updates are left
+// untouched.
+func (b *DigitalOceanBuffer) update(ctx context.Context, resources ...string) *Snapshot {
+	refreshID := refreshIDFrom(ctx)
+	preparers := b.preparers()
+	startedAt := time.Now()
+
+	var wg sync.WaitGroup
+	results := make([]refreshResult, 0, len(resources))
+	for _, resource := range resources {
+		prepare, ok := preparers[resource]
+		if !ok {
+			continue
+		}
+
+		results = append(results, refreshResult{resource: resource})
+		wg.Add(1)
+		go func(result *refreshResult, prepare func(context.Context) (func(*Snapshot), error)) {
+			defer wg.Done()
+
+			info := &refreshInfo{id: refreshID}
+			resourceStartedAt := time.Now()
+			result.apply, result.err = prepare(withRefreshInfo(ctx, info))
+			result.duration = time.Now().Sub(resourceStartedAt)
+			result.requests = int(atomic.LoadInt64(&info.requests))
+
+			b.logRefresh(ctx, result)
+		}(&results[len(results)-1], prepare)
+	}
+	wg.Wait()
+
+	requests := 0
+	for _, result := range results {
+		requests += result.requests
+	}
+
+	rate := b.lastRate()
+	interval := b.nextInterval(resources[0], b.intervalFor(resources), requests, rate)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	next := b.snapshot.next(refreshID)
+	next.maxStaleness = b.maxStaleness
+	next.queryDuration = time.Now().Sub(startedAt)
+	next.rate = rate
+
+	for _, result := range results {
+		if result.err == nil {
+			result.apply(next)
+		}
+		recordRefresh(next, result, interval)
+	}
+
+	b.snapshot = next
+	return next
+}
+
+// acquire blocks until one of the slots for concurrent requests against the
+// DigitalOcean API is free and claims it, or until ctx is done. A claimed slot
+// must be freed by release.
+func (b *DigitalOceanBuffer) acquire(ctx context.Context) error {
+	select {
+	case b.requests <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot claimed by acquire.
+func (b *DigitalOceanBuffer) release() {
+	<-b.requests
+}
+
+// listPages calls list for every page of a listing. The first page is
+// requested on its own; if its response reports the total number of
+// elements, the remaining pages are then requested concurrently, otherwise
+// they are followed one after another using the response links. list may be
+// called from several goroutines at once.
+func (b *DigitalOceanBuffer) listPages(ctx context.Context, resource string, list func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error)) error {
+	request := func(pageOpt *godo.ListOptions) (*godo.Response, error) {
+		return b.requestWithRetry(ctx, resource, pageOpt, list)
+	}
+
 	pageOpt := newPageOpt()
+	resp, err := request(pageOpt)
+	if err != nil {
+		return err
+	}
 
-	for {
-		droplets, resp, err := b.client.Droplets.List(ctx, pageOpt)
-		b.logSearchRequest("Droplets", pageOpt, len(droplets), err)
+	if resp.Links == nil || resp.Links.IsLastPage() {
+		return nil
+	}
+
+	if resp.Meta != nil && resp.Meta.Total > 0 {
+		pages := (resp.Meta.Total + pageOpt.PerPage - 1) / pageOpt.PerPage
+
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			firstErr error
+		)
+		for page := 2; page <= pages; page++ {
+			wg.Add(1)
+			go func(page int) {
+				defer wg.Done()
+
+				pageOpt := newPageOpt()
+				pageOpt.Page = page
+				if _, err := request(pageOpt); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}(page)
+		}
+		wg.Wait()
+
+		return firstErr
+	}
 
+	for {
+		page, err := resp.Links.CurrentPage()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		for _, d := range droplets {
-			dropletList = append(dropletList, d)
+		pageOpt.Page = page + 1
+		resp, err = request(pageOpt)
+		if err != nil {
+			return err
 		}
 
 		if resp.Links == nil || resp.Links.IsLastPage() {
-			break
+			return nil
 		}
+	}
+}
 
-		page, err := resp.Links.CurrentPage()
+// get requests a single resource which is not paginated, such as the account,
+// with the same retries and limits as the pages requested by listPages.
+func (b *DigitalOceanBuffer) get(ctx context.Context, resource string, get func(ctx context.Context) (*godo.Response, error)) error {
+	_, err := b.requestWithRetry(ctx, resource, newPageOpt(), func(ctx context.Context, _ *godo.ListOptions) (int, *godo.Response, error) {
+		resp, err := get(ctx)
+		if err != nil {
+			return 0, resp, err
+		}
+		return 1, resp, nil
+	})
+	return err
+}
+
+// requestContext derives the context for a single request against the
+// DigitalOcean API from the context of the refresh it belongs to.
+func (b *DigitalOceanBuffer) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.requestTimeout > 0 {
+		return context.WithTimeout(ctx, b.requestTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (b *DigitalOceanBuffer) prepareAccount(ctx context.Context) (func(*Snapshot), error) {
+	var account *godo.Account
+
+	err := b.get(ctx, "Account", func(ctx context.Context) (*godo.Response, error) {
+		a, resp, err := b.client.Account.Get(ctx)
+		account = a
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func(s *Snapshot) {
+		s.account = account
+	}, nil
+}
+
+// An App is a struct holding an App Platform app together with its most
+// recent deployments, newest first.
+type App struct {
+	app         *godo.App
+	deployments []*godo.Deployment
+}
+
+func (b *DigitalOceanBuffer) listApps(ctx context.Context) ([]*godo.App, error) {
+	appList := []*godo.App{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Apps", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		apps, resp, err := b.client.Apps.List(ctx, pageOpt)
+
+		mu.Lock()
+		appList = append(appList, apps...)
+		mu.Unlock()
+
+		return len(apps), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return appList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareApps(ctx context.Context) (func(*Snapshot), error) {
+	list, err := b.listApps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]App, 0, len(list))
+	for _, a := range list {
+		app := App{app: a}
+
+		err := b.get(ctx, "AppDeployments", func(ctx context.Context) (*godo.Response, error) {
+			deployments, resp, err := b.client.Apps.ListDeployments(ctx, a.ID, &godo.ListOptions{Page: 1, PerPage: recentDeployments})
+			app.deployments = deployments
+			return resp, err
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		pageOpt.Page = page + 1
+		apps = append(apps, app)
 	}
 
-	return dropletList, nil
+	return func(s *Snapshot) {
+		s.appList = apps
+	}, nil
 }
 
-func (b *DigitalOceanBuffer) prepareDroplets() {
-	counters := make(map[DropletCounter]int)
+// A Billing is a struct holding the balance and the most recent invoices of
+// an account.
+type Billing struct {
+	balance  *godo.Balance
+	invoices []godo.InvoiceListItem
+}
 
-	droplets, err := b.listDroplets()
-	b.logLastError(err)
+func (b *DigitalOceanBuffer) prepareBilling(ctx context.Context) (func(*Snapshot), error) {
+	billing := &Billing{}
 
-	for _, d := range droplets {
-		c := DropletCounter{
-			d.Status,
-			d.Region.Slug,
-			d.Size.Slug,
-			d.Size.PriceHourly,
-			d.Size.PriceMonthly,
-			strings.Join(d.Tags, ","),
+	err := b.get(ctx, "Balance", func(ctx context.Context) (*godo.Response, error) {
+		balance, resp, err := b.client.Balance.Get(ctx)
+		billing.balance = balance
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.get(ctx, "Invoices", func(ctx context.Context) (*godo.Response, error) {
+		invoices, resp, err := b.client.Invoices.List(ctx, &godo.ListOptions{Page: 1, PerPage: recentInvoices})
+		if invoices != nil {
+			billing.invoices = invoices.Invoices
 		}
-		counters[c]++
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	b.Droplets = counters
+	return func(s *Snapshot) {
+		s.billing = billing
+	}, nil
 }
 
-func (b *DigitalOceanBuffer) listFips() ([]godo.FloatingIP, error) {
-	ctx := context.TODO()
-	fipList := []godo.FloatingIP{}
-	pageOpt := newPageOpt()
+func (b *DigitalOceanBuffer) listInvoiceItems(ctx context.Context, invoiceUUID string) ([]godo.InvoiceItem, error) {
+	itemList := []godo.InvoiceItem{}
+	var mu sync.Mutex
 
-	for {
-		fips, resp, err := b.client.FloatingIPs.List(ctx, pageOpt)
-		b.logSearchRequest("FloatingIPs", pageOpt, len(fips), err)
+	err := b.listPages(ctx, "InvoiceItems", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		invoice, resp, err := b.client.Invoices.Get(ctx, invoiceUUID, pageOpt)
+		if err != nil {
+			return 0, resp, err
+		}
+
+		mu.Lock()
+		itemList = append(itemList, invoice.InvoiceItems...)
+		mu.Unlock()
+
+		return len(invoice.InvoiceItems), resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return itemList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareInvoiceItems(ctx context.Context) (func(*Snapshot), error) {
+	amounts := make(map[InvoiceItemCounter]float64)
+
+	var list *godo.InvoiceList
+	err := b.get(ctx, "Invoices", func(ctx context.Context) (*godo.Response, error) {
+		l, resp, err := b.client.Invoices.List(ctx, &godo.ListOptions{Page: 1, PerPage: 1})
+		list = l
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Items are exported for the most recent closed invoice and for the
+	// preview of the current billing period.
+	invoices := make(map[string]godo.InvoiceListItem)
+	if len(list.Invoices) > 0 {
+		invoices[invoiceClosed] = list.Invoices[0]
+	}
+	if list.InvoicePreview.InvoiceUUID != "" {
+		invoices[invoicePreview] = list.InvoicePreview
+	}
 
+	for invoice, item := range invoices {
+		items, err := b.listInvoiceItems(ctx, item.InvoiceUUID)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, f := range fips {
-			fipList = append(fipList, f)
+		for _, i := range items {
+			amount, ok := parseAmount(i.Amount)
+			if !ok {
+				continue
+			}
+
+			c := InvoiceItemCounter{
+				invoice,
+				item.InvoicePeriod,
+				i.Product,
+				i.ProjectName,
+				i.GroupDescription,
+				i.Category,
+			}
+			amounts[c] += amount
 		}
+	}
 
-		if resp.Links == nil || resp.Links.IsLastPage() {
-			break
+	return func(s *Snapshot) {
+		s.invoiceItems = amounts
+	}, nil
+}
+
+func (b *DigitalOceanBuffer) listCertificates(ctx context.Context) ([]godo.Certificate, error) {
+	certificateList := []godo.Certificate{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Certificates", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		certificates, resp, err := b.client.Certificates.List(ctx, pageOpt)
+
+		mu.Lock()
+		certificateList = append(certificateList, certificates...)
+		mu.Unlock()
+
+		return len(certificates), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return certificateList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareCertificates(ctx context.Context) (func(*Snapshot), error) {
+	certificates, err := b.listCertificates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(s *Snapshot) {
+		s.certificateList = certificates
+	}, nil
+}
+
+// A DatabaseCluster is a struct holding a database cluster together with its
+// read replicas, connection pools and trusted sources.
+type DatabaseCluster struct {
+	database      godo.Database
+	replicas      []godo.DatabaseReplica
+	pools         []godo.DatabasePool
+	firewallRules []godo.DatabaseFirewallRule
+}
+
+// Engines of database clusters supporting connection pools and read replicas
+// respectively. Listing either for other engines fails.
+var (
+	poolEngines    = map[string]bool{"pg": true}
+	replicaEngines = map[string]bool{"pg": true, "mysql": true}
+)
+
+func (b *DigitalOceanBuffer) listDatabases(ctx context.Context) ([]godo.Database, error) {
+	databaseList := []godo.Database{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Databases", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		databases, resp, err := b.client.Databases.List(ctx, pageOpt)
+
+		mu.Lock()
+		databaseList = append(databaseList, databases...)
+		mu.Unlock()
+
+		return len(databases), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return databaseList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareDatabases(ctx context.Context) (func(*Snapshot), error) {
+	databases, err := b.listDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]DatabaseCluster, 0, len(databases))
+	for _, db := range databases {
+		cluster := DatabaseCluster{database: db}
+
+		if replicaEngines[db.EngineSlug] {
+			err := b.get(ctx, "DatabaseReplicas", func(ctx context.Context) (*godo.Response, error) {
+				replicas, resp, err := b.client.Databases.ListReplicas(ctx, db.ID, nil)
+				cluster.replicas = replicas
+				return resp, err
+			})
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		page, err := resp.Links.CurrentPage()
+		if poolEngines[db.EngineSlug] {
+			err := b.get(ctx, "DatabasePools", func(ctx context.Context) (*godo.Response, error) {
+				pools, resp, err := b.client.Databases.ListPools(ctx, db.ID, nil)
+				cluster.pools = pools
+				return resp, err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := b.get(ctx, "DatabaseFirewallRules", func(ctx context.Context) (*godo.Response, error) {
+			rules, resp, err := b.client.Databases.GetFirewallRules(ctx, db.ID)
+			cluster.firewallRules = rules
+			return resp, err
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		pageOpt.Page = page + 1
+		clusters = append(clusters, cluster)
+	}
+
+	return func(s *Snapshot) {
+		s.databaseList = clusters
+	}, nil
+}
+
+func (b *DigitalOceanBuffer) listDomains(ctx context.Context) ([]godo.Domain, error) {
+	domainList := []godo.Domain{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Domains", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		domains, resp, err := b.client.Domains.List(ctx, pageOpt)
+
+		mu.Lock()
+		domainList = append(domainList, domains...)
+		mu.Unlock()
+
+		return len(domains), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return domainList, nil
+}
+
+func (b *DigitalOceanBuffer) listDomainRecords(ctx context.Context, domain string) ([]godo.DomainRecord, error) {
+	recordList := []godo.DomainRecord{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "DomainRecords", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		records, resp, err := b.client.Domains.Records(ctx, domain, pageOpt)
+
+		mu.Lock()
+		recordList = append(recordList, records...)
+		mu.Unlock()
+
+		return len(records), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recordList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareDomains(ctx context.Context) (func(*Snapshot), error) {
+	counters := make(map[DomainRecordCounter]int)
+	minTTLs := make(map[string]int)
+	var addressRecords []AddressRecord
+
+	domains, err := b.listDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, domain := range domains {
+		records, err := b.listDomainRecords(ctx, domain.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range records {
+			c := DomainRecordCounter{
+				domain.Name,
+				r.Type,
+			}
+			counters[c]++
+
+			if r.TTL > 0 {
+				if ttl, ok := minTTLs[domain.Name]; !ok || r.TTL < ttl {
+					minTTLs[domain.Name] = r.TTL
+				}
+			}
+
+			if r.Type == "A" || r.Type == "AAAA" {
+				addressRecords = append(addressRecords, AddressRecord{
+					domain.Name,
+					r.Name,
+					r.Type,
+					r.Data,
+				})
+			}
+		}
+	}
+
+	return func(s *Snapshot) {
+		s.domainRecords = counters
+		s.domainMinTTLs = minTTLs
+		s.addressRecords = addressRecords
+	}, nil
+}
+
+func (b *DigitalOceanBuffer) listDroplets(ctx context.Context) ([]godo.Droplet, error) {
+	dropletList := []godo.Droplet{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Droplets", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		droplets, resp, err := b.client.Droplets.List(ctx, pageOpt)
+
+		mu.Lock()
+		dropletList = append(dropletList, droplets...)
+		mu.Unlock()
+
+		return len(droplets), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dropletList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareDroplets(ctx context.Context) (func(*Snapshot), error) {
+	costs := make(map[DropletCostCounter]Cost)
+	features := make(map[DropletFeatureCounter]int)
+
+	droplets, err := b.listDroplets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range droplets {
+		tags, promoted := b.dropletTags.labels(d.Tags)
+		cc := DropletCostCounter{
+			d.Region.Slug,
+			d.Size.Slug,
+			tags,
+			promoted,
+		}
+		costs[cc] = costs[cc].add(Cost{d.Size.PriceHourly, d.Size.PriceMonthly})
+
+		for _, feature := range dropletFeatures {
+			fc := DropletFeatureCounter{
+				feature,
+				hasFeature(d.Features, feature),
+				d.Region.Slug,
+				tags,
+				promoted,
+			}
+			features[fc]++
+		}
+	}
+
+	return func(s *Snapshot) {
+		s.dropletCosts = costs
+		s.dropletFeatures = features
+		s.dropletList = droplets
+		s.countDroplets()
+	}, nil
+}
+
+// hasFeature reports whether feature is among the features of a Droplet.
+func hasFeature(features []string, feature string) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *DigitalOceanBuffer) listFirewalls(ctx context.Context) ([]godo.Firewall, error) {
+	firewallList := []godo.Firewall{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Firewalls", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		firewalls, resp, err := b.client.Firewalls.List(ctx, pageOpt)
+
+		mu.Lock()
+		firewallList = append(firewallList, firewalls...)
+		mu.Unlock()
+
+		return len(firewalls), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return firewallList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareFirewalls(ctx context.Context) (func(*Snapshot), error) {
+	firewalls, err := b.listFirewalls(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(s *Snapshot) {
+		s.firewallList = firewalls
+	}, nil
+}
+
+func (b *DigitalOceanBuffer) listFips(ctx context.Context) ([]godo.FloatingIP, error) {
+	fipList := []godo.FloatingIP{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "FloatingIPs", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		fips, resp, err := b.client.FloatingIPs.List(ctx, pageOpt)
+
+		mu.Lock()
+		fipList = append(fipList, fips...)
+		mu.Unlock()
+
+		return len(fips), resp, err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return fipList, nil
 }
 
-func (b *DigitalOceanBuffer) prepareFloatingIPs() {
+func (b *DigitalOceanBuffer) prepareFloatingIPs(ctx context.Context) (func(*Snapshot), error) {
 	counters := make(map[FlipCounter]int)
+	costs := make(map[string]Cost)
 
-	floatingIPs, err := b.listFips()
-	b.logLastError(err)
+	floatingIPs, err := b.listFips(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, fip := range floatingIPs {
 		var status string
@@ -218,48 +1167,111 @@ func (b *DigitalOceanBuffer) prepareFloatingIPs() {
 			fip.Region.Slug,
 		}
 		counters[c]++
+
+		costs[fip.Region.Slug] = costs[fip.Region.Slug].add(floatingIPCost(fip))
 	}
 
-	b.FloatingIPs = counters
+	return func(s *Snapshot) {
+		s.floatingIPs = counters
+		s.floatingIPList = floatingIPs
+		s.floatingIPCosts = costs
+	}, nil
 }
 
-func (b *DigitalOceanBuffer) listLoadBalancers() ([]godo.LoadBalancer, error) {
-	ctx := context.TODO()
-	lbList := []godo.LoadBalancer{}
-	pageOpt := newPageOpt()
+// A KubernetesCluster is a struct holding a DOKS cluster together with the
+// versions it can be upgraded to.
+type KubernetesCluster struct {
+	cluster  *godo.KubernetesCluster
+	upgrades []*godo.KubernetesVersion
+}
 
-	for {
-		lbs, resp, err := b.client.LoadBalancers.List(ctx, pageOpt)
-		b.logSearchRequest("LoadBalancers", pageOpt, len(lbs), err)
+func (b *DigitalOceanBuffer) listKubernetesClusters(ctx context.Context) ([]*godo.KubernetesCluster, error) {
+	clusterList := []*godo.KubernetesCluster{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "KubernetesClusters", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		clusters, resp, err := b.client.Kubernetes.List(ctx, pageOpt)
+
+		mu.Lock()
+		clusterList = append(clusterList, clusters...)
+		mu.Unlock()
+
+		return len(clusters), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareKubernetes(ctx context.Context) (func(*Snapshot), error) {
+	workers := make(map[int]bool)
+
+	list, err := b.listKubernetesClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	clusters := make([]KubernetesCluster, 0, len(list))
+	for _, k := range list {
+		cluster := KubernetesCluster{cluster: k}
+
+		err := b.get(ctx, "KubernetesUpgrades", func(ctx context.Context) (*godo.Response, error) {
+			upgrades, resp, err := b.client.Kubernetes.GetUpgrades(ctx, k.ID)
+			cluster.upgrades = upgrades
+			return resp, err
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		for _, lb := range lbs {
-			lbList = append(lbList, lb)
+		for _, pool := range k.NodePools {
+			for _, node := range pool.Nodes {
+				if id, err := strconv.Atoi(node.DropletID); err == nil {
+					workers[id] = true
+				}
+			}
 		}
 
-		if resp.Links == nil || resp.Links.IsLastPage() {
-			break
-		}
+		clusters = append(clusters, cluster)
+	}
 
-		page, err := resp.Links.CurrentPage()
-		if err != nil {
-			return nil, err
-		}
+	return func(s *Snapshot) {
+		s.kubernetesList = clusters
+		s.kubernetesWorkers = workers
+		s.countDroplets()
+	}, nil
+}
 
-		pageOpt.Page = page + 1
+func (b *DigitalOceanBuffer) listLoadBalancers(ctx context.Context) ([]godo.LoadBalancer, error) {
+	lbList := []godo.LoadBalancer{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "LoadBalancers", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		lbs, resp, err := b.client.LoadBalancers.List(ctx, pageOpt)
+
+		mu.Lock()
+		lbList = append(lbList, lbs...)
+		mu.Unlock()
+
+		return len(lbs), resp, err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return lbList, nil
 }
 
-func (b *DigitalOceanBuffer) prepareLoadBalancers() {
+func (b *DigitalOceanBuffer) prepareLoadBalancers(ctx context.Context) (func(*Snapshot), error) {
 	counters := make(map[LoadBalancerCounter]int)
+	costs := make(map[string]Cost)
 
-	loadBallancers, err := b.listLoadBalancers()
-	b.logLastError(err)
+	loadBallancers, err := b.listLoadBalancers(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, lb := range loadBallancers {
 		c := LoadBalancerCounter{
@@ -267,48 +1279,287 @@ func (b *DigitalOceanBuffer) prepareLoadBalancers() {
 			lb.Region.Slug,
 		}
 		counters[c]++
+
+		costs[lb.Region.Slug] = costs[lb.Region.Slug].add(loadBalancerCost(lb))
 	}
 
-	b.LoadBalancers = counters
+	return func(s *Snapshot) {
+		s.loadBalancers = counters
+		s.loadBalancerCosts = costs
+		s.loadBalancerList = loadBallancers
+	}, nil
 }
 
-func (b *DigitalOceanBuffer) listTags() ([]godo.Tag, error) {
-	ctx := context.TODO()
-	tagList := []godo.Tag{}
-	pageOpt := newPageOpt()
+// A Registry is a struct holding the container registry of an account
+// together with its subscription, repositories and most recent garbage
+// collection.
+type Registry struct {
+	registry          *godo.Registry
+	subscription      *godo.RegistrySubscription
+	repositories      []RegistryRepository
+	garbageCollection *godo.GarbageCollection
+}
 
-	for {
-		tags, resp, err := b.client.Tags.List(ctx, pageOpt)
-		b.logSearchRequest("Tags", pageOpt, len(tags), err)
+// RegistryRepository is a struct holding a repository of a container
+// registry and the number and total size of its manifests.
+type RegistryRepository struct {
+	repository    *godo.Repository
+	manifests     int
+	manifestBytes uint64
+	updatedAt     time.Time
+}
+
+func (b *DigitalOceanBuffer) listRepositories(ctx context.Context, registry string) ([]*godo.Repository, error) {
+	repositoryList := []*godo.Repository{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "RegistryRepositories", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		repositories, resp, err := b.client.Registry.ListRepositories(ctx, registry, pageOpt)
+
+		mu.Lock()
+		repositoryList = append(repositoryList, repositories...)
+		mu.Unlock()
+
+		return len(repositories), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repositoryList, nil
+}
+
+func (b *DigitalOceanBuffer) listRepositoryManifests(ctx context.Context, registry, repository string) ([]*godo.RepositoryManifest, error) {
+	manifestList := []*godo.RepositoryManifest{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "RegistryManifests", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		manifests, resp, err := b.client.Registry.ListRepositoryManifests(ctx, registry, repository, pageOpt)
+
+		mu.Lock()
+		manifestList = append(manifestList, manifests...)
+		mu.Unlock()
+
+		return len(manifests), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifestList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareRegistry(ctx context.Context) (func(*Snapshot), error) {
+	registry := &Registry{}
+
+	// An account without a container registry is not an error.
+	err := b.get(ctx, "Registry", func(ctx context.Context) (*godo.Response, error) {
+		r, resp, err := b.client.Registry.Get(ctx)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return resp, nil
+		}
+		registry.registry = r
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if registry.registry == nil {
+		return func(s *Snapshot) {
+			s.registry = nil
+		}, nil
+	}
+	name := registry.registry.Name
+
+	err = b.get(ctx, "RegistrySubscription", func(ctx context.Context) (*godo.Response, error) {
+		subscription, resp, err := b.client.Registry.GetSubscription(ctx)
+		registry.subscription = subscription
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.get(ctx, "RegistryGarbageCollections", func(ctx context.Context) (*godo.Response, error) {
+		gcs, resp, err := b.client.Registry.ListGarbageCollections(ctx, name, &godo.ListOptions{Page: 1, PerPage: 1})
+		if len(gcs) > 0 {
+			registry.garbageCollection = gcs[0]
+		}
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repositories, err := b.listRepositories(ctx, name)
+	if err != nil {
+		return nil, err
+	}
 
+	for _, repository := range repositories {
+		manifests, err := b.listRepositoryManifests(ctx, name, repository.Name)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, t := range tags {
-			tagList = append(tagList, t)
+		r := RegistryRepository{
+			repository: repository,
+			manifests:  len(manifests),
+		}
+		if repository.LatestTag != nil {
+			r.updatedAt = repository.LatestTag.UpdatedAt
+		}
+		for _, m := range manifests {
+			r.manifestBytes += m.SizeBytes
+			if m.UpdatedAt.After(r.updatedAt) {
+				r.updatedAt = m.UpdatedAt
+			}
 		}
 
-		if resp.Links == nil || resp.Links.IsLastPage() {
-			break
+		registry.repositories = append(registry.repositories, r)
+	}
+
+	return func(s *Snapshot) {
+		s.registry = registry
+	}, nil
+}
+
+func (b *DigitalOceanBuffer) listSnapshots(ctx context.Context) ([]godo.Snapshot, error) {
+	snapshotList := []godo.Snapshot{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Snapshots", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		snapshots, resp, err := b.client.Snapshots.List(ctx, pageOpt)
+
+		mu.Lock()
+		snapshotList = append(snapshotList, snapshots...)
+		mu.Unlock()
+
+		return len(snapshots), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshotList, nil
+}
+
+func (b *DigitalOceanBuffer) listImages(ctx context.Context) ([]godo.Image, error) {
+	imageList := []godo.Image{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Images", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		images, resp, err := b.client.Images.ListUser(ctx, pageOpt)
+
+		mu.Lock()
+		imageList = append(imageList, images...)
+		mu.Unlock()
+
+		return len(images), resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return imageList, nil
+}
+
+func (b *DigitalOceanBuffer) prepareSnapshots(ctx context.Context) (func(*Snapshot), error) {
+	counters := make(map[SnapshotCounter]int)
+	sizes := make(map[SnapshotCounter]float64)
+	costs := make(map[SnapshotCounter]Cost)
+	oldest := make(map[SnapshotSourceCounter]time.Time)
+
+	snapshots, err := b.listSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := b.listImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Private images include the Droplet Snapshots listed above, so only
+	// custom images are added. They are stored and billed like Snapshots.
+	for _, img := range images {
+		if img.Type != "custom" {
+			continue
 		}
+		snapshots = append(snapshots, godo.Snapshot{
+			ID:            strconv.Itoa(img.ID),
+			Name:          img.Name,
+			ResourceType:  imageResourceType,
+			Regions:       img.Regions,
+			SizeGigaBytes: img.SizeGigaBytes,
+			Created:       img.Created,
+		})
+	}
 
-		page, err := resp.Links.CurrentPage()
+	for _, sn := range snapshots {
+		// A Snapshot is stored, and billed, in each region it is
+		// available in.
+		for _, region := range sn.Regions {
+			c := SnapshotCounter{
+				region,
+				sn.ResourceType,
+			}
+			counters[c]++
+			sizes[c] += sn.SizeGigaBytes
+			costs[c] = costs[c].add(snapshotCost(sn))
+		}
+
+		if sn.ResourceID == "" {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, sn.Created)
 		if err != nil {
-			return nil, err
+			continue
 		}
+		sc := SnapshotSourceCounter{
+			sn.ResourceID,
+			sn.ResourceType,
+		}
+		if t, ok := oldest[sc]; !ok || created.Before(t) {
+			oldest[sc] = created
+		}
+	}
 
-		pageOpt.Page = page + 1
+	return func(s *Snapshot) {
+		s.snapshots = counters
+		s.snapshotSizes = sizes
+		s.snapshotCosts = costs
+		s.oldestSnapshots = oldest
+	}, nil
+}
+
+func (b *DigitalOceanBuffer) listTags(ctx context.Context) ([]godo.Tag, error) {
+	tagList := []godo.Tag{}
+	var mu sync.Mutex
+
+	err := b.listPages(ctx, "Tags", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		tags, resp, err := b.client.Tags.List(ctx, pageOpt)
+
+		mu.Lock()
+		tagList = append(tagList, tags...)
+		mu.Unlock()
+
+		return len(tags), resp, err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return tagList, nil
 }
 
-func (b *DigitalOceanBuffer) prepareTags() {
+func (b *DigitalOceanBuffer) prepareTags(ctx context.Context) (func(*Snapshot), error) {
 	counters := make(map[TagCounter]int)
 
-	tags, err := b.listTags()
-	b.logLastError(err)
+	tags, err := b.listTags(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, t := range tags {
 		// Note: Currently only Droplets may be tagged.
@@ -320,48 +1571,42 @@ func (b *DigitalOceanBuffer) prepareTags() {
 		counters[c] = counters[c] + t.Resources.Droplets.Count
 	}
 
-	b.Tags = counters
+	return func(s *Snapshot) {
+		s.tags = counters
+	}, nil
 }
 
-func (b *DigitalOceanBuffer) listVolumes() ([]godo.Volume, error) {
-	ctx := context.TODO()
+func (b *DigitalOceanBuffer) listVolumes(ctx context.Context) ([]godo.Volume, error) {
 	volumeList := []godo.Volume{}
-	volumeParams := &godo.ListVolumeParams{
-		ListOptions: newPageOpt(),
-	}
-
-	for {
-		volumes, resp, err := b.client.Storage.ListVolumes(ctx, volumeParams)
-		b.logSearchRequest("Volumes", volumeParams.ListOptions, len(volumes), err)
-
-		if err != nil {
-			return nil, err
-		}
+	var mu sync.Mutex
 
-		for _, v := range volumes {
-			volumeList = append(volumeList, v)
-		}
-
-		if resp.Links == nil || resp.Links.IsLastPage() {
-			break
+	err := b.listPages(ctx, "Volumes", func(ctx context.Context, pageOpt *godo.ListOptions) (int, *godo.Response, error) {
+		volumeParams := &godo.ListVolumeParams{
+			ListOptions: pageOpt,
 		}
+		volumes, resp, err := b.client.Storage.ListVolumes(ctx, volumeParams)
 
-		page, err := resp.Links.CurrentPage()
-		if err != nil {
-			return nil, err
-		}
+		mu.Lock()
+		volumeList = append(volumeList, volumes...)
+		mu.Unlock()
 
-		volumeParams.ListOptions.Page = page + 1
+		return len(volumes), resp, err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return volumeList, nil
 }
 
-func (b *DigitalOceanBuffer) prepareVolumes() {
+func (b *DigitalOceanBuffer) prepareVolumes(ctx context.Context) (func(*Snapshot), error) {
 	counters := make(map[VolumeCounter]int)
+	costs := make(map[string]Cost)
 
-	volumes, err := b.listVolumes()
-	b.logLastError(err)
+	volumes, err := b.listVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, v := range volumes {
 		var status string
@@ -378,44 +1623,143 @@ func (b *DigitalOceanBuffer) prepareVolumes() {
 			strconv.FormatInt(v.SizeGigaBytes, 10),
 		}
 		counters[c]++
+
+		costs[v.Region.Slug] = costs[v.Region.Slug].add(volumeCost(v))
 	}
 
-	b.Volumes = counters
+	return func(s *Snapshot) {
+		s.volumes = counters
+		s.volumeCosts = costs
+	}, nil
 }
 
-func (b *DigitalOceanBuffer) refresh() {
-	b.refreshID, _ = uuid.NewV4()
-	log := logrus.WithField("refreshID", b.refreshID)
+// refresh refreshes the given resource types and returns the time to wait
+// before refreshing them again.
+func (b *DigitalOceanBuffer) refresh(ctx context.Context, resources []string) time.Duration {
+	refreshID, _ := uuid.NewV4()
+	ctx = withRefreshInfo(ctx, &refreshInfo{id: refreshID})
+	log := logrus.WithFields(logrus.Fields{
+		"refreshID": refreshID,
+		"resources": strings.Join(resources, ","),
+	})
 
 	log.Infoln("Starting DigitalOcean data refresh")
-	startedAt := time.Now()
 
-	b.prepareDroplets()
-	b.prepareFloatingIPs()
-	b.prepareLoadBalancers()
-	b.prepareTags()
-	b.prepareVolumes()
+	if b.refreshTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.refreshTimeout)
+		defer cancel()
+	}
+
+	snapshot := b.update(ctx, resources...)
+	status := snapshot.RefreshStatus()[resources[0]]
+
+	log.WithFields(logrus.Fields{
+		"duration":        snapshot.QueryDuration().String(),
+		"refreshInterval": status.interval.String(),
+	}).Infoln("Finished DigitalOcean data refresh")
+
+	return status.interval
+}
 
-	defer func() {
-		duration := time.Now().Sub(startedAt)
-		b.QueryDuration = duration
-		log.WithField("duration", duration.String()).Infoln("Finished DigitalOcean data refresh")
-	}()
+// watch refreshes each group of resource types sharing a refresh interval on
+// its own schedule until ctx is cancelled.
+func (b *DigitalOceanBuffer) watch(ctx context.Context) {
+	groups := b.groupResources(b.resources)
+
+	var wg sync.WaitGroup
+	for _, resources := range groups {
+		wg.Add(1)
+		go func(resources []string) {
+			defer wg.Done()
+			b.watchGroup(ctx, resources)
+		}(resources)
+	}
+	wg.Wait()
 }
 
-func (b *DigitalOceanBuffer) watch() {
-	b.refresh()
+func (b *DigitalOceanBuffer) watchGroup(ctx context.Context, resources []string) {
+	interval := b.refresh(ctx, resources)
 	for {
 		select {
-		case <-time.After(b.refreshInterval):
-			b.refresh()
+		case <-time.After(interval):
+			interval = b.refresh(ctx, resources)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// groupResources groups resource types by their configured refresh interval,
+// preserving the order in which they are given.
+func (b *DigitalOceanBuffer) groupResources(resources []string) [][]string {
+	var groups [][]string
+	index := make(map[time.Duration]int)
+
+	for _, resource := range resources {
+		interval := b.intervalFor([]string{resource})
+		i, ok := index[interval]
+		if !ok {
+			i = len(groups)
+			index[interval] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], resource)
+	}
+
+	return groups
+}
+
+// intervalFor retrieves the configured refresh interval of a group of
+// resource types, all of which share the same interval. Resource types without
+// an interval of their own fall back to their default interval, if any, and
+// then to the refresh interval of the buffer.
+func (b *DigitalOceanBuffer) intervalFor(resources []string) time.Duration {
+	if len(resources) > 0 {
+		if interval, ok := b.resourceInterval[resources[0]]; ok && interval > 0 {
+			return interval
+		}
+		if interval, ok := DefaultResourceRefreshIntervals()[resources[0]]; ok {
+			return time.Duration(interval) * time.Second
 		}
 	}
+	return b.refreshInterval
 }
 
-func (b *DigitalOceanBuffer) logSearchRequest(resource string, pageOpt *godo.ListOptions, elementsCount int, err error) {
+// nextInterval computes the time to wait before the next refresh of a group
+// of resource types. In adaptive mode the rate limit budget is shared between
+// groups in proportion to the requests per hour each makes at its configured
+// interval. When the groups together would exceed the budget, every interval
+// is stretched by the same factor, so that an expensive group is not slowed
+// down to leave room for a cheap one. Otherwise, or while the rate limit is
+// unknown, the configured interval is used.
+func (b *DigitalOceanBuffer) nextInterval(group string, base time.Duration, requests int, rate godo.Rate) time.Duration {
+	if !b.adaptive || b.rateLimitBudget <= 0 || rate.Limit == 0 || requests == 0 || base <= 0 {
+		return base
+	}
+
+	b.demandMu.Lock()
+	if b.demand == nil {
+		b.demand = make(map[string]float64)
+	}
+	b.demand[group] = float64(requests) * float64(time.Hour) / float64(base)
+	var demand float64
+	for _, d := range b.demand {
+		demand += d
+	}
+	b.demandMu.Unlock()
+
+	budget := b.rateLimitBudget * float64(rate.Limit)
+	if demand <= budget {
+		return base
+	}
+
+	return time.Duration(float64(base) * demand / budget)
+}
+
+func (b *DigitalOceanBuffer) logSearchRequest(ctx context.Context, resource string, pageOpt *godo.ListOptions, elementsCount int, err error) {
 	log := logrus.WithFields(logrus.Fields{
-		"refreshID": b.refreshID,
+		"refreshID": refreshIDFrom(ctx),
 		"page":      pageOpt.Page,
 		"perPage":   pageOpt.PerPage,
 		"found":     elementsCount,
@@ -429,20 +1773,102 @@ func (b *DigitalOceanBuffer) logSearchRequest(resource string, pageOpt *godo.Lis
 	}
 }
 
-func (b *DigitalOceanBuffer) logLastError(err error) {
+func (b *DigitalOceanBuffer) logLastError(ctx context.Context, err error) {
 	if err != nil {
-		logrus.WithField("refreshID", b.refreshID).WithError(err).Errorln("Error while requesting DigitalOcean")
+		logrus.WithField("refreshID", refreshIDFrom(ctx)).WithError(err).Errorln("Error while requesting DigitalOcean")
 	}
 }
 
-func NewDigitalOceanBuffer(client *godo.Client, refreshInterval int) *DigitalOceanBuffer {
-	interval := time.Duration(refreshInterval) * time.Second
+func (b *DigitalOceanBuffer) logRefresh(ctx context.Context, result *refreshResult) {
+	b.logLastError(ctx, result.err)
+	logrus.WithFields(logrus.Fields{
+		"refreshID": refreshIDFrom(ctx),
+		"resource":  result.resource,
+		"duration":  result.duration.String(),
+		"requests":  result.requests,
+	}).Debugln("Finished refreshing resource")
+}
+
+// recordRefresh updates the refresh status of a resource type in the given
+// Snapshot with the outcome of its most recent refresh.
+func recordRefresh(s *Snapshot, result refreshResult, interval time.Duration) {
+	status := s.refreshStatus[result.resource]
+	status.success = result.err == nil
+	status.duration = result.duration
+	status.requests = result.requests
+	status.interval = interval
+	if result.err == nil {
+		status.lastSuccess = time.Now()
+	} else {
+		class := classifyError(result.err)
+		s.refreshErrors[RefreshErrorCounter{result.resource, class}]++
+		if class == errorClassTimeout {
+			s.refreshTimeouts[result.resource]++
+		}
+	}
+
+	s.refreshStatus[result.resource] = status
+}
+
+// classifyError maps an error returned by godo to a coarse error class
+// suitable for use as a metric label.
+func classifyError(err error) string {
+	if errResp, ok := err.(*godo.ErrorResponse); ok && errResp.Response != nil {
+		code := errResp.Response.StatusCode
+
+		switch {
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return errorClassAuth
+		case code == http.StatusTooManyRequests:
+			return errorClassRateLimit
+		case code >= 500:
+			return errorClassServerError
+		default:
+			return errorClassClientError
+		}
+	}
+
+	if netErr, ok := err.(net.Error); ok {
+		if netErr.Timeout() {
+			return errorClassTimeout
+		}
+		return errorClassNetwork
+	}
+
+	return errorClassOther
+}
+
+// NewDigitalOceanBuffer creates a new DigitalOceanBuffer and starts refreshing
+// it in the background until ctx is cancelled.
+func NewDigitalOceanBuffer(ctx context.Context, client *godo.Client, config BufferConfig) *DigitalOceanBuffer {
+	concurrency := config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
 	buffer := &DigitalOceanBuffer{
-		client:          client,
-		refreshInterval: interval,
+		client:           client,
+		refreshInterval:  config.RefreshInterval,
+		resourceInterval: config.ResourceRefreshIntervals,
+		resources:        config.Resources,
+		maxStaleness:     config.MaxStaleness,
+		refreshTimeout:   config.RefreshTimeout,
+		requestTimeout:   config.RequestTimeout,
+		requests:         make(chan struct{}, concurrency),
+		maxRetries:       config.MaxRetries,
+		retryBaseDelay:   defaultRetryBaseDelay,
+		retryMaxDelay:    defaultRetryMaxDelay,
+		adaptive:         config.AdaptiveRefresh,
+		rateLimitBudget:  config.RateLimitBudget,
+		dropletTags:      config.DropletTags,
+		snapshot:         newSnapshot(),
+	}
+	buffer.snapshot.dropletTags = config.DropletTags
+	if buffer.resources == nil {
+		buffer.resources = Resources()
 	}
 
-	go buffer.watch()
+	go buffer.watch(ctx)
 
 	return buffer
 }
@@ -1,13 +1,21 @@
 package digitaloceanexporter
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/digitalocean/godo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/oauth2"
 )
@@ -20,33 +28,179 @@ func TestDroplets(t *testing.T) {
 		{`{"droplets": [
         {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}},
         {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}}]}`,
-			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc3", price_hourly: 0.014880, price_monthly: 5.0}: 2}},
+			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc3"}: 2}},
 		{`{"droplets": [
         {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}},
         {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}},
         {"status":"active", "size":{"slug":"2gb", "price_hourly": 0.029760, "price_monthly": 20.0}, "region":{"slug":"nyc3"}}]}`,
-			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc3", price_hourly: 0.014880, price_monthly: 5.0}: 2,
-				DropletCounter{status: "active", size: "2gb", region: "nyc3", price_hourly: 0.029760, price_monthly: 20.0}: 1}},
+			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc3"}: 2,
+				DropletCounter{status: "active", size: "2gb", region: "nyc3"}: 1}},
 		{`{"droplets": [
         {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}},
         {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}},
         {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc2"}},
         {"status":"active", "size":{"slug":"2gb", "price_hourly": 0.029760, "price_monthly": 20.0}, "region":{"slug":"nyc3"}}]}`,
-			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc3", price_hourly: 0.014880, price_monthly: 5.0}: 2,
-				DropletCounter{status: "active", size: "1gb", region: "nyc2", price_hourly: 0.014880, price_monthly: 5.0}:  1,
-				DropletCounter{status: "active", size: "2gb", region: "nyc3", price_hourly: 0.029760, price_monthly: 20.0}: 1}},
+			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc3"}: 2,
+				DropletCounter{status: "active", size: "1gb", region: "nyc2"}: 1,
+				DropletCounter{status: "active", size: "2gb", region: "nyc3"}: 1}},
 	}
 
 	for _, tt := range dropletTests {
 		apiServer(t, "/v2/droplets", tt.resp, func() {
 			dob := getDOBuffer()
-			dob.prepareDroplets()
+			dob.update(context.Background(), resourceDroplets)
 			dos := NewDigitalOceanService(dob)
 			assert.Equal(t, tt.expected, dos.Droplets(), "they should be equal")
 		})
 	}
 }
 
+func TestDropletCosts(t *testing.T) {
+	resp := `{"droplets": [
+        {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}},
+        {"status":"off", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}},
+        {"status":"active", "size":{"slug":"2gb", "price_hourly": 0.029760, "price_monthly": 20.0}, "region":{"slug":"nyc3"}, "tags":["production"]}]}`
+	expected := map[DropletCostCounter]Cost{
+		DropletCostCounter{region: "nyc3", size: "1gb"}:                     Cost{hourly: 0.029760, monthly: 10.0},
+		DropletCostCounter{region: "nyc3", size: "2gb", tags: "production"}: Cost{hourly: 0.029760, monthly: 20.0},
+	}
+
+	apiServer(t, "/v2/droplets", resp, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceDroplets)
+		assert.Equal(t, expected, dob.Snapshot().DropletCosts(), "they should be equal")
+	})
+}
+
+func TestResourceCosts(t *testing.T) {
+	var costTests = []struct {
+		cost     Cost
+		expected float64
+	}{
+		{volumeCost(godo.Volume{SizeGigaBytes: 100}), 10.0},
+		{snapshotCost(godo.Snapshot{SizeGigaBytes: 50}), 3.0},
+		{loadBalancerCost(godo.LoadBalancer{SizeUnit: 2}), 24.0},
+		{loadBalancerCost(godo.LoadBalancer{SizeSlug: "lb-medium"}), 36.0},
+		{loadBalancerCost(godo.LoadBalancer{}), 12.0},
+		{floatingIPCost(godo.FloatingIP{}), 5.0},
+		{floatingIPCost(godo.FloatingIP{Droplet: &godo.Droplet{ID: 1}}), 0},
+	}
+
+	for _, tt := range costTests {
+		assert.InDelta(t, tt.expected, tt.cost.monthly, 1e-9, "they should be equal")
+		assert.InDelta(t, tt.expected/hoursPerMonth, tt.cost.hourly, 1e-9, "they should be equal")
+	}
+}
+
+func TestDropletFeatures(t *testing.T) {
+	resp := `{"droplets": [
+        {"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "features":["backups", "monitoring"], "backup_ids":[1, 2], "tags":["production"]},
+        {"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "features":["ipv6"], "tags":["production"]}]}`
+
+	apiServer(t, "/v2/droplets", resp, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceDroplets)
+
+		expected := map[DropletFeatureCounter]int{
+			DropletFeatureCounter{feature: "backups", enabled: true, region: "nyc3", tags: "production"}:             1,
+			DropletFeatureCounter{feature: "backups", enabled: false, region: "nyc3", tags: "production"}:            1,
+			DropletFeatureCounter{feature: "ipv6", enabled: true, region: "nyc3", tags: "production"}:                1,
+			DropletFeatureCounter{feature: "ipv6", enabled: false, region: "nyc3", tags: "production"}:               1,
+			DropletFeatureCounter{feature: "private_networking", enabled: false, region: "nyc3", tags: "production"}: 2,
+			DropletFeatureCounter{feature: "monitoring", enabled: true, region: "nyc3", tags: "production"}:          1,
+			DropletFeatureCounter{feature: "monitoring", enabled: false, region: "nyc3", tags: "production"}:         1,
+		}
+		assert.Equal(t, expected, dob.Snapshot().DropletFeatures(), "they should be equal")
+		assert.Equal(t, []int{1, 2}, dob.Snapshot().DropletList()[0].BackupIDs, "they should be equal")
+	})
+}
+
+func TestDropletTags(t *testing.T) {
+	var tagTests = []struct {
+		config   TagConfig
+		tags     []string
+		expected []string
+	}{
+		// Tags are sorted so that the same set always yields the same label.
+		{TagConfig{}, []string{"web", "production"}, []string{"production,web", ""}},
+		{TagConfig{}, []string{"production", "web"}, []string{"production,web", ""}},
+		// Only included tags are kept.
+		{TagConfig{Include: regexp.MustCompile("^env:")}, []string{"web", "env:production", "host-1"}, []string{"env:production", ""}},
+		// Promoted tags are exported with their value, or true for plain tags.
+		{TagConfig{Promote: []string{"env", "web", "team"}}, []string{"web", "env:production"}, []string{"env:production,web", "production,true,"}},
+	}
+
+	for _, tt := range tagTests {
+		tags, promoted := tt.config.labels(tt.tags)
+		assert.Equal(t, tt.expected, []string{tags, promoted}, "they should be equal")
+	}
+
+	config := TagConfig{Promote: []string{"env", "cost-center"}}
+	assert.Equal(t, []string{"tag_env", "tag_cost_center"}, config.labelNames(), "they should be equal")
+	assert.Equal(t, []string{"production", ""}, config.splitTagValues("production,"), "they should be equal")
+	assert.NoError(t, config.Validate())
+
+	// Promoted tags must yield distinct label names.
+	for _, promote := range [][]string{{"env", "env"}, {"env.x", "env_x"}, {""}} {
+		assert.Error(t, TagConfig{Promote: promote}.Validate())
+	}
+
+	resp := `{"droplets": [
+        {"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "tags":["env:production", "web"]},
+        {"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "tags":["web", "env:production"]}]}`
+
+	apiServer(t, "/v2/droplets", resp, func() {
+		dob := getDOBuffer()
+		dob.dropletTags = config
+		dob.snapshot.dropletTags = config
+		dob.update(context.Background(), resourceDroplets)
+
+		expected := map[DropletCounter]int{
+			DropletCounter{status: "active", region: "nyc3", size: "1gb", tags: "env:production,web", promoted: "production,"}: 2,
+		}
+		assert.Equal(t, expected, dob.Snapshot().Droplets(), "they should be equal")
+
+		exporter, err := New(NewDigitalOceanService(dob), []string{"droplets"})
+		assert.NoError(t, err)
+
+		registry := prometheus.NewPedanticRegistry()
+		assert.NoError(t, registry.Register(exporter))
+		_, err = registry.Gather()
+		assert.NoError(t, err)
+	})
+}
+
+func TestDropletInfo(t *testing.T) {
+	var dropletTests = []struct {
+		resp     string
+		expected [][]string
+	}{
+		{`{"droplets": [
+        {"id": 1, "name": "web-1", "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"},
+         "image":{"slug":"ubuntu-16-04-x64", "distribution":"Ubuntu"}, "vpc_uuid":"5a4981aa-9653-4bd1-bef5-d6bff52042e4",
+         "networks":{"v4":[{"ip_address":"10.128.0.2", "type":"private"}, {"ip_address":"104.236.32.182", "type":"public"}],
+                     "v6":[{"ip_address":"2604:a880:0:1010::18a:a001", "type":"public"}]}}]}`,
+			[][]string{{"1", "web-1", "nyc3", "1gb", "ubuntu-16-04-x64", "Ubuntu", "5a4981aa-9653-4bd1-bef5-d6bff52042e4",
+				"104.236.32.182", "10.128.0.2", "2604:a880:0:1010::18a:a001", "active"}}},
+		{`{"droplets": [
+        {"id": 2, "name": "db-1", "status":"off", "size":{"slug":"2gb"}, "region":{"slug":"lon1"}}]}`,
+			[][]string{{"2", "db-1", "lon1", "2gb", "", "", "", "", "", "", "off"}}},
+	}
+
+	for _, tt := range dropletTests {
+		apiServer(t, "/v2/droplets", tt.resp, func() {
+			dob := getDOBuffer()
+			dob.update(context.Background(), resourceDroplets)
+
+			var labels [][]string
+			for _, d := range dob.Snapshot().DropletList() {
+				labels = append(labels, dropletInfoLabels(&d))
+			}
+			assert.Equal(t, tt.expected, labels, "they should be equal")
+		})
+	}
+}
+
 func TestFloatingIPs(t *testing.T) {
 	var fipTests = []struct {
 		resp     string
@@ -75,7 +229,7 @@ func TestFloatingIPs(t *testing.T) {
 	for _, tt := range fipTests {
 		apiServer(t, "/v2/floating_ips", tt.resp, func() {
 			dob := getDOBuffer()
-			dob.prepareFloatingIPs()
+			dob.update(context.Background(), resourceFloatingIPs)
 			dos := NewDigitalOceanService(dob)
 			assert.Equal(t, tt.expected, dos.FloatingIPs(), "they should be equal")
 		})
@@ -110,13 +264,446 @@ func TestLoadBalancers(t *testing.T) {
 	for _, tt := range lbTests {
 		apiServer(t, "/v2/load_balancers", tt.resp, func() {
 			dob := getDOBuffer()
-			dob.prepareLoadBalancers()
+			dob.update(context.Background(), resourceLoadBalancers)
 			dos := NewDigitalOceanService(dob)
 			assert.Equal(t, tt.expected, dos.LoadBalancers(), "they should be equal")
 		})
 	}
 }
 
+func TestKubernetes(t *testing.T) {
+	resps := map[string]string{
+		"/v2/kubernetes/clusters": `{"kubernetes_clusters": [
+            {"id": "k1", "name": "prod", "region": "nyc3", "version": "1.29.1-do.0", "status": {"state": "running"},
+             "auto_upgrade": true, "node_pools": [
+                {"id": "p1", "name": "default", "size": "s-2vcpu-4gb", "count": 2, "nodes": [
+                    {"id": "n1", "name": "default-1", "droplet_id": "1", "status": {"state": "running"}},
+                    {"id": "n2", "name": "default-2", "droplet_id": "2", "status": {"state": "provisioning"}}]},
+                {"id": "p2", "name": "batch", "size": "s-4vcpu-8gb", "count": 0, "auto_scale": true, "min_nodes": 0, "max_nodes": 5}]}]}`,
+		"/v2/kubernetes/clusters/k1/upgrades": `{"available_upgrade_versions": [{"slug": "1.29.2-do.0"}]}`,
+		"/v2/droplets": `{"droplets": [
+            {"id": 1, "status":"active", "size":{"slug":"s-2vcpu-4gb"}, "region":{"slug":"nyc3"}},
+            {"id": 2, "status":"active", "size":{"slug":"s-2vcpu-4gb"}, "region":{"slug":"nyc3"}},
+            {"id": 3, "status":"active", "size":{"slug":"s-2vcpu-4gb"}, "region":{"slug":"nyc3"}}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+
+		// Whether a Droplet is a DOKS worker is unknown until the clusters
+		// have been refreshed.
+		dob.update(context.Background(), resourceDroplets)
+		expected := map[DropletCounter]int{
+			DropletCounter{status: "active", size: "s-2vcpu-4gb", region: "nyc3"}: 3,
+		}
+		assert.Equal(t, expected, dob.Snapshot().Droplets(), "they should be equal")
+
+		dob.update(context.Background(), resourceKubernetes)
+		expected = map[DropletCounter]int{
+			DropletCounter{status: "active", size: "s-2vcpu-4gb", region: "nyc3", doksWorker: "true"}:  2,
+			DropletCounter{status: "active", size: "s-2vcpu-4gb", region: "nyc3", doksWorker: "false"}: 1,
+		}
+		assert.Equal(t, expected, dob.Snapshot().Droplets(), "they should be equal")
+
+		c := NewKubernetesCollector(dob)
+
+		upgrade := collectValues(t, c, "digitalocean_kubernetes_cluster_upgrade_available")
+		assert.Equal(t, map[string]float64{"k1": 1}, upgrade, "they should be equal")
+
+		desired := collectValues(t, c, "digitalocean_kubernetes_node_pool_desired_nodes")
+		assert.Equal(t, map[string]float64{"k1,default,s-2vcpu-4gb": 2, "k1,batch,s-4vcpu-8gb": 0}, desired, "they should be equal")
+
+		maxNodes := collectValues(t, c, "digitalocean_kubernetes_node_pool_auto_scale_max_nodes")
+		assert.Equal(t, map[string]float64{"k1,batch": 5}, maxNodes, "they should be equal")
+
+		nodes := collectValues(t, c, "digitalocean_kubernetes_node_info")
+		expectedNodes := map[string]float64{
+			"1,k1,default-1,default,running":      1,
+			"2,k1,default-2,default,provisioning": 1,
+		}
+		assert.Equal(t, expectedNodes, nodes, "they should be equal")
+	})
+}
+
+func TestLoadBalancerInfo(t *testing.T) {
+	resps := map[string]string{
+		"/v2/load_balancers": `{"load_balancers": [
+            {"id": "abc", "name": "web", "region":{"slug":"nyc3"}, "status": "active", "size_unit": 2, "tag": "web",
+             "redirect_http_to_https": true, "sticky_sessions": {"type": "cookies"},
+             "forwarding_rules": [{"entry_protocol": "https"}, {"entry_protocol": "http"}, {"entry_protocol": "https"}],
+             "health_check": {"protocol": "http", "port": 80, "path": "/", "check_interval_seconds": 10,
+                              "response_timeout_seconds": 5, "healthy_threshold": 5, "unhealthy_threshold": 3}},
+            {"id": "def", "name": "api", "region":{"slug":"nyc3"}, "status": "active", "size": "lb-medium", "tag": "api"},
+            {"id": "ghi", "name": "db", "region":{"slug":"nyc3"}, "status": "active", "droplet_ids": [1, 2, 3]}]}`,
+		"/v2/droplets": `{"droplets": [
+            {"id": 1, "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "tags":["web"]},
+            {"id": 2, "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "tags":["web", "production"]}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceLoadBalancers, resourceDroplets)
+		c := NewLoadBalancerInfoCollector(dob)
+
+		// Load Balancers using a legacy size report no size units.
+		units := collectValues(t, c, "digitalocean_load_balancer_size_units")
+		assert.Equal(t, map[string]float64{"abc": 2, "def": 3, "ghi": 1}, units, "they should be equal")
+
+		backends := collectValues(t, c, "digitalocean_load_balancer_backend_droplets")
+		assert.Equal(t, map[string]float64{"abc": 2, "def": 0, "ghi": 3}, backends, "they should be equal")
+
+		empty := collectValues(t, c, "digitalocean_load_balancer_backend_tag_empty")
+		assert.Equal(t, map[string]float64{"abc,web": 0, "def,api": 1}, empty, "they should be equal")
+
+		rules := collectValues(t, c, "digitalocean_load_balancer_forwarding_rules")
+		assert.Equal(t, map[string]float64{"https,abc": 2, "http,abc": 1}, rules, "they should be equal")
+
+		interval := collectValues(t, c, "digitalocean_load_balancer_health_check_interval_seconds")
+		assert.Equal(t, map[string]float64{"abc": 10}, interval, "they should be equal")
+	})
+}
+
+func TestFirewalls(t *testing.T) {
+	resps := map[string]string{
+		"/v2/firewalls": `{"firewalls": [
+            {"id": "fw1", "name": "web", "status": "succeeded", "droplet_ids": [1], "tags": ["web"],
+             "inbound_rules": [
+                {"protocol": "tcp", "ports": "22", "sources": {"addresses": ["0.0.0.0/0", "::/0"]}},
+                {"protocol": "tcp", "ports": "443", "sources": {"addresses": ["0.0.0.0/0"]}},
+                {"protocol": "tcp", "ports": "3000-6000", "sources": {"addresses": ["0.0.0.0/0"]}},
+                {"protocol": "udp", "ports": "all", "sources": {"addresses": ["10.0.0.0/8"]}},
+                {"protocol": "icmp", "sources": {"addresses": ["0.0.0.0/0"]}}],
+             "outbound_rules": [{"protocol": "tcp", "ports": "0", "destinations": {"addresses": ["0.0.0.0/0"]}}],
+             "pending_changes": [{"droplet_id": 1, "status": "waiting"}]}]}`,
+		"/v2/droplets": `{"droplets": [
+            {"id": 1, "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}},
+            {"id": 2, "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "tags":["web"]},
+            {"id": 3, "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}, "tags":["db"]},
+            {"id": 4, "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"ams3"}, "tags":["web"]}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		c := NewFirewallCollector(dob)
+
+		// Without Firewalls every Droplet would appear to be unprotected.
+		dob.update(context.Background(), resourceDroplets)
+		uncovered := collectValues(t, c, "digitalocean_droplets_without_firewall_count")
+		assert.Equal(t, map[string]float64{}, uncovered, "they should be equal")
+
+		dob.update(context.Background(), resourceFirewalls)
+		uncovered = collectValues(t, c, "digitalocean_droplets_without_firewall_count")
+		assert.Equal(t, map[string]float64{"ams3": 0, "nyc3": 1}, uncovered, "they should be equal")
+
+		rules := collectValues(t, c, "digitalocean_firewall_rules")
+		assert.Equal(t, map[string]float64{"inbound,fw1": 5, "outbound,fw1": 1}, rules, "they should be equal")
+
+		pending := collectValues(t, c, "digitalocean_firewall_pending_changes")
+		assert.Equal(t, map[string]float64{"fw1": 1}, pending, "they should be equal")
+
+		open := collectValues(t, c, "digitalocean_firewall_sensitive_port_open")
+		expected := map[string]float64{
+			"fw1,22,tcp":   1,
+			"fw1,3306,tcp": 1,
+			"fw1,3389,tcp": 1,
+			"fw1,5432,tcp": 1,
+			"fw1,5900,tcp": 1,
+		}
+		assert.Equal(t, expected, open, "they should be equal")
+	})
+}
+
+func TestParsePortRange(t *testing.T) {
+	var portTests = []struct {
+		ports     string
+		low, high int
+		ok        bool
+	}{
+		{"22", 22, 22, true},
+		{"8000-9000", 8000, 9000, true},
+		{"0", 1, 65535, true},
+		{"all", 1, 65535, true},
+		{"ssh", 0, 0, false},
+	}
+
+	for _, tt := range portTests {
+		low, high, ok := parsePortRange(tt.ports)
+		assert.Equal(t, tt.ok, ok, tt.ports)
+		assert.Equal(t, tt.low, low, tt.ports)
+		assert.Equal(t, tt.high, high, tt.ports)
+	}
+}
+
+func TestApps(t *testing.T) {
+	resps := map[string]string{
+		"/v2/apps": `{"apps": [
+            {"id": "a1", "tier_slug": "basic", "region": {"slug": "nyc"},
+             "spec": {"name": "web", "services": [{"name": "api"}, {"name": "frontend"}], "workers": [{"name": "queue"}]},
+             "active_deployment": {"id": "d2", "phase": "ACTIVE"}}]}`,
+		"/v2/apps/a1/deployments": `{"deployments": [
+            {"id": "d4", "phase": "BUILDING", "created_at": "2019-07-10T12:00:00Z"},
+            {"id": "d3", "phase": "ERROR", "created_at": "2019-07-10T11:00:00Z"},
+            {"id": "d2", "phase": "ACTIVE", "created_at": "2019-07-10T10:00:00Z"},
+            {"id": "d1", "phase": "SUPERSEDED", "created_at": "2019-07-09T10:00:00Z"}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceApps)
+		c := NewAppCollector(dob)
+
+		active := collectValues(t, c, "digitalocean_app_active_deployment_phase")
+		assert.Equal(t, map[string]float64{"a1,ACTIVE": 1}, active, "they should be equal")
+
+		created := collectValues(t, c, "digitalocean_app_last_deployment_timestamp_seconds")
+		assert.Equal(t, map[string]float64{"a1": 1562760000}, created, "they should be equal")
+
+		phase := collectValues(t, c, "digitalocean_app_last_deployment_phase")
+		assert.Equal(t, map[string]float64{"a1,BUILDING": 1}, phase, "they should be equal")
+
+		// The deployment in progress has not finished yet, so the outcome is
+		// that of the failed deployment before it.
+		success := collectValues(t, c, "digitalocean_app_last_deployment_success")
+		assert.Equal(t, map[string]float64{"a1": 0}, success, "they should be equal")
+
+		deployments := collectValues(t, c, "digitalocean_app_deployments")
+		expected := map[string]float64{"a1,BUILDING": 1, "a1,ERROR": 1, "a1,ACTIVE": 1, "a1,SUPERSEDED": 1}
+		assert.Equal(t, expected, deployments, "they should be equal")
+
+		components := collectValues(t, c, "digitalocean_app_components")
+		expected = map[string]float64{"a1,service": 2, "a1,worker": 1, "a1,static_site": 0, "a1,job": 0, "a1,function": 0}
+		assert.Equal(t, expected, components, "they should be equal")
+
+		info := collectValues(t, c, "digitalocean_app_info")
+		assert.Equal(t, map[string]float64{"a1,web,nyc,basic": 1}, info, "they should be equal")
+	})
+}
+
+func TestCertificates(t *testing.T) {
+	resps := map[string]string{
+		"/v2/certificates": `{"certificates": [
+            {"id": "c1", "name": "web", "type": "lets_encrypt", "state": "verified",
+             "not_after": "2017-02-22T00:23:00Z", "dns_names": ["example.com", "www.example.com"]},
+            {"id": "c2", "name": "old", "type": "custom", "state": "verified",
+             "not_after": "2017-01-01T00:00:00Z", "dns_names": ["old.example.com"]},
+            {"id": "c3", "name": "new", "type": "lets_encrypt", "state": "pending"}]}`,
+		"/v2/load_balancers": `{"load_balancers": [{"id": "abc", "region":{"slug":"nyc3"},
+             "forwarding_rules": [{"entry_protocol": "https", "certificate_id": "c1"}, {"entry_protocol": "http"}]}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		c := NewCertificateCollector(dob)
+
+		dob.update(context.Background(), resourceCertificates)
+		notAfter := collectValues(t, c, "digitalocean_certificate_not_after_timestamp_seconds")
+		assert.Equal(t, map[string]float64{"c1": 1487722980, "c2": 1483228800}, notAfter, "they should be equal")
+
+		names := collectValues(t, c, "digitalocean_certificate_dns_names")
+		assert.Equal(t, map[string]float64{"c1": 2, "c2": 1, "c3": 0}, names, "they should be equal")
+
+		// Without Load Balancers every certificate would appear to be unused.
+		inUse := collectValues(t, c, "digitalocean_certificate_in_use")
+		assert.Equal(t, map[string]float64{}, inUse, "they should be equal")
+
+		dob.update(context.Background(), resourceLoadBalancers)
+		inUse = collectValues(t, c, "digitalocean_certificate_in_use")
+		assert.Equal(t, map[string]float64{"c1": 1, "c2": 0, "c3": 0}, inUse, "they should be equal")
+	})
+}
+
+func TestDatabases(t *testing.T) {
+	resps := map[string]string{
+		"/v2/databases": `{"databases": [
+            {"id": "pg1", "name": "main", "engine": "pg", "version": "16", "num_nodes": 2, "size": "db-s-2vcpu-4gb",
+             "region": "nyc3", "status": "online", "maintenance_window": {"day": "tuesday", "hour": "08:00:00", "pending": true}},
+            {"id": "redis1", "name": "cache", "engine": "redis", "version": "7", "num_nodes": 1, "size": "db-s-1vcpu-1gb",
+             "region": "nyc3", "status": "online"}]}`,
+		"/v2/databases/pg1/replicas": `{"replicas": [
+            {"id": "r1", "status": "online"}, {"id": "r2", "status": "online"}, {"id": "r3", "status": "forking"}]}`,
+		"/v2/databases/pg1/pools": `{"pools": [{"name": "app", "db": "defaultdb", "mode": "transaction", "size": 10}]}`,
+		"/v2/databases/pg1/firewall": `{"rules": [
+            {"type": "ip_addr", "value": "192.0.2.1"}, {"type": "droplet", "value": "1"}, {"type": "droplet", "value": "2"}]}`,
+		"/v2/databases/redis1/firewall": `{"rules": []}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceDatabases)
+		c := NewDatabaseCollector(dob)
+
+		nodes := collectValues(t, c, "digitalocean_database_nodes")
+		assert.Equal(t, map[string]float64{"pg1": 2, "redis1": 1}, nodes, "they should be equal")
+
+		replicas := collectValues(t, c, "digitalocean_database_replicas")
+		assert.Equal(t, map[string]float64{"pg1,online": 2, "pg1,forking": 1}, replicas, "they should be equal")
+
+		// Connection pools are only supported by PostgreSQL.
+		pools := collectValues(t, c, "digitalocean_database_pools")
+		assert.Equal(t, map[string]float64{"pg1": 1}, pools, "they should be equal")
+
+		sizes := collectValues(t, c, "digitalocean_database_pool_size")
+		assert.Equal(t, map[string]float64{"defaultdb,pg1,transaction,app": 10}, sizes, "they should be equal")
+
+		rules := collectValues(t, c, "digitalocean_database_firewall_rules")
+		assert.Equal(t, map[string]float64{"pg1,ip_addr": 1, "pg1,droplet": 2}, rules, "they should be equal")
+
+		pending := collectValues(t, c, "digitalocean_database_maintenance_pending")
+		assert.Equal(t, map[string]float64{"pg1": 1}, pending, "they should be equal")
+	})
+}
+
+func TestNextMaintenanceWindow(t *testing.T) {
+	// 2019-07-09 was a Tuesday.
+	now := time.Date(2019, 7, 9, 10, 0, 0, 0, time.UTC)
+
+	var windowTests = []struct {
+		day, hour string
+		expected  time.Time
+		ok        bool
+	}{
+		{"tuesday", "12:00:00", time.Date(2019, 7, 9, 12, 0, 0, 0, time.UTC), true},
+		{"tuesday", "08:00:00", time.Date(2019, 7, 16, 8, 0, 0, 0, time.UTC), true},
+		{"Sunday", "23:30", time.Date(2019, 7, 14, 23, 30, 0, 0, time.UTC), true},
+		{"monday", "00:00:00", time.Date(2019, 7, 15, 0, 0, 0, 0, time.UTC), true},
+		{"someday", "00:00:00", time.Time{}, false},
+		{"monday", "noon", time.Time{}, false},
+	}
+
+	for _, tt := range windowTests {
+		next, ok := nextMaintenanceWindow(&godo.DatabaseMaintenanceWindow{Day: tt.day, Hour: tt.hour}, now)
+		assert.Equal(t, tt.ok, ok, tt.day+" "+tt.hour)
+		assert.Equal(t, tt.expected, next, tt.day+" "+tt.hour)
+	}
+}
+
+func TestDomains(t *testing.T) {
+	resps := map[string]string{
+		"/v2/domains": `{"domains": [{"name": "example.com", "ttl": 1800}]}`,
+		"/v2/domains/example.com/records": `{"domain_records": [
+            {"type": "NS", "name": "@", "data": "ns1.digitalocean.com", "ttl": 1800},
+            {"type": "A", "name": "@", "data": "192.0.2.1", "ttl": 3600},
+            {"type": "A", "name": "www", "data": "192.0.2.2", "ttl": 300},
+            {"type": "A", "name": "lb", "data": "192.0.2.3", "ttl": 300},
+            {"type": "A", "name": "old", "data": "192.0.2.4", "ttl": 300},
+            {"type": "AAAA", "name": "@", "data": "2001:db8:0:0::1", "ttl": 3600}]}`,
+		"/v2/droplets": `{"droplets": [
+            {"id": 1, "status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"},
+             "networks": {"v4": [{"ip_address": "192.0.2.1", "type": "public"}],
+                          "v6": [{"ip_address": "2001:DB8::1", "type": "public"}]}}]}`,
+		"/v2/floating_ips":   `{"floating_ips": [{"ip": "192.0.2.2", "region":{"slug":"nyc3"}}]}`,
+		"/v2/load_balancers": `{"load_balancers": [{"id": "abc", "ip": "192.0.2.3", "region":{"slug":"nyc3"}}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		c := NewDomainCollector(dob)
+
+		dob.update(context.Background(), resourceDomains)
+		records := collectValues(t, c, "digitalocean_domain_records")
+		assert.Equal(t, map[string]float64{"example.com,A": 4, "example.com,AAAA": 1, "example.com,NS": 1}, records, "they should be equal")
+
+		ttls := collectValues(t, c, "digitalocean_domain_min_ttl_seconds")
+		assert.Equal(t, map[string]float64{"example.com": 300}, ttls, "they should be equal")
+
+		// Without the resources owning addresses every record would appear
+		// to be dangling.
+		dangling := collectValues(t, c, "digitalocean_domain_record_dangling")
+		assert.Equal(t, map[string]float64{}, dangling, "they should be equal")
+
+		dob.update(context.Background(), resourceDroplets, resourceFloatingIPs, resourceLoadBalancers)
+		dangling = collectValues(t, c, "digitalocean_domain_record_dangling")
+		assert.Equal(t, map[string]float64{"192.0.2.4,example.com,old,A": 1}, dangling, "they should be equal")
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	resps := map[string]string{
+		"/v2/registry": `{"registry": {"name": "reg", "region": "nyc3", "storage_usage_bytes": 536870912,
+             "storage_usage_bytes_updated_at": "2019-07-10T12:00:00Z"}}`,
+		"/v2/registry/subscription": `{"subscription": {"tier": {"slug": "basic", "included_storage_bytes": 5368709120}}}`,
+		"/v2/registry/reg/garbage-collections": `{"garbage_collections": [
+            {"status": "succeeded", "updated_at": "2019-07-09T12:00:00Z", "freed_bytes": 1024}]}`,
+		"/v2/registry/reg/repositories": `{"repositories": [
+            {"name": "web", "tag_count": 3, "latest_tag": {"updated_at": "2019-07-08T12:00:00Z"}},
+            {"name": "api", "tag_count": 1}]}`,
+		"/v2/registry/reg/repositories/web/digests": `{"manifests": [
+            {"size_bytes": 100, "updated_at": "2019-07-10T10:00:00Z"},
+            {"size_bytes": 200, "updated_at": "2019-07-07T10:00:00Z"}]}`,
+		"/v2/registry/reg/repositories/api/digests": `{"manifests": []}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceRegistry)
+		c := NewRegistryCollector(dob)
+
+		utilization := collectValues(t, c, "digitalocean_registry_storage_utilization_ratio")
+		assert.Equal(t, map[string]float64{"reg": 0.1}, utilization, "they should be equal")
+
+		tags := collectValues(t, c, "digitalocean_registry_repository_tags")
+		assert.Equal(t, map[string]float64{"reg,web": 3, "reg,api": 1}, tags, "they should be equal")
+
+		sizes := collectValues(t, c, "digitalocean_registry_repository_manifest_size_bytes")
+		assert.Equal(t, map[string]float64{"reg,web": 300, "reg,api": 0}, sizes, "they should be equal")
+
+		updated := collectValues(t, c, "digitalocean_registry_repository_updated_timestamp_seconds")
+		assert.Equal(t, map[string]float64{"reg,web": 1562752800}, updated, "they should be equal")
+
+		gc := collectValues(t, c, "digitalocean_registry_garbage_collection_status")
+		assert.Equal(t, map[string]float64{"reg,succeeded": 1}, gc, "they should be equal")
+	})
+
+	// An account without a container registry is not an error.
+	apiServerWithStatus(t, "/v2/registry", http.StatusNotFound, `{"id": "not_found"}`, func() {
+		dob := getDOBuffer()
+		s := dob.update(context.Background(), resourceRegistry)
+
+		assert.True(t, s.RefreshStatus()[resourceRegistry].success, "the refresh should succeed")
+		assert.Nil(t, s.Registry())
+	})
+}
+
+func TestSnapshots(t *testing.T) {
+	resps := map[string]string{
+		"/v2/snapshots": `{"snapshots": [
+            {"id": "1", "resource_id": "100", "resource_type": "droplet", "regions": ["nyc3"], "size_gigabytes": 2.5, "created_at": "2018-01-01T00:00:00Z"},
+            {"id": "2", "resource_id": "100", "resource_type": "droplet", "regions": ["nyc3", "lon1"], "size_gigabytes": 3, "created_at": "2017-06-01T00:00:00Z"},
+            {"id": "3", "resource_id": "abc", "resource_type": "volume", "regions": ["nyc3"], "size_gigabytes": 10, "created_at": "2018-02-01T00:00:00Z"}]}`,
+		"/v2/images": `{"images": [
+            {"id": 2, "type": "snapshot", "regions": ["nyc3"], "size_gigabytes": 3},
+            {"id": 4, "type": "custom", "regions": ["nyc3"], "size_gigabytes": 1.5, "created_at": "2018-03-01T00:00:00Z"}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceSnapshots)
+		s := dob.Snapshot()
+
+		assert.Equal(t, map[SnapshotCounter]int{
+			SnapshotCounter{region: "nyc3", resourceType: "droplet"}: 2,
+			SnapshotCounter{region: "lon1", resourceType: "droplet"}: 1,
+			SnapshotCounter{region: "nyc3", resourceType: "volume"}:  1,
+			SnapshotCounter{region: "nyc3", resourceType: "image"}:   1,
+		}, s.Snapshots(), "they should be equal")
+
+		assert.Equal(t, map[SnapshotCounter]float64{
+			SnapshotCounter{region: "nyc3", resourceType: "droplet"}: 5.5,
+			SnapshotCounter{region: "lon1", resourceType: "droplet"}: 3,
+			SnapshotCounter{region: "nyc3", resourceType: "volume"}:  10,
+			SnapshotCounter{region: "nyc3", resourceType: "image"}:   1.5,
+		}, s.SnapshotSizes(), "they should be equal")
+
+		assert.Equal(t, map[SnapshotSourceCounter]time.Time{
+			SnapshotSourceCounter{resourceID: "100", resourceType: "droplet"}: time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC),
+			SnapshotSourceCounter{resourceID: "abc", resourceType: "volume"}:  time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC),
+		}, s.OldestSnapshots(), "they should be equal")
+
+		oldest := collectValues(t, NewSnapshotCollector(dob), "digitalocean_snapshots_oldest_age_seconds")
+		assert.Len(t, oldest, 2, "every Droplet and Volume should have an oldest Snapshot")
+	})
+}
+
 func TestTags(t *testing.T) {
 	var tagTests = []struct {
 		resp     string
@@ -135,7 +722,7 @@ func TestTags(t *testing.T) {
 	for _, tt := range tagTests {
 		apiServer(t, "/v2/tags", tt.resp, func() {
 			dob := getDOBuffer()
-			dob.prepareTags()
+			dob.update(context.Background(), resourceTags)
 			dos := NewDigitalOceanService(dob)
 			assert.Equal(t, tt.expected, dos.Tags(), "they should be equal")
 		})
@@ -170,13 +757,454 @@ func TestVolumes(t *testing.T) {
 	for _, tt := range volumeTests {
 		apiServer(t, "/v2/volumes", tt.resp, func() {
 			dob := getDOBuffer()
-			dob.prepareVolumes()
+			dob.update(context.Background(), resourceVolumes)
 			dos := NewDigitalOceanService(dob)
 			assert.Equal(t, tt.expected, dos.Volumes(), "they should be equal")
 		})
 	}
 }
 
+func TestRefreshErrors(t *testing.T) {
+	var errorTests = []struct {
+		status   int
+		expected map[RefreshErrorCounter]int
+	}{
+		{401, map[RefreshErrorCounter]int{RefreshErrorCounter{resource: "droplets", class: "auth"}: 1}},
+		{429, map[RefreshErrorCounter]int{RefreshErrorCounter{resource: "droplets", class: "rate_limit"}: 1}},
+		{503, map[RefreshErrorCounter]int{RefreshErrorCounter{resource: "droplets", class: "server_error"}: 1}},
+		{404, map[RefreshErrorCounter]int{RefreshErrorCounter{resource: "droplets", class: "client_error"}: 1}},
+	}
+
+	for _, tt := range errorTests {
+		apiServerWithStatus(t, "/v2/droplets", tt.status, `{"id": "error", "message": "error"}`, func() {
+			dob := getDOBuffer()
+			dob.update(context.Background(), resourceDroplets)
+			dos := NewDigitalOceanService(dob)
+			assert.Equal(t, tt.expected, dos.RefreshErrors(), "they should be equal")
+			assert.False(t, dos.RefreshStatus()["droplets"].success, "refresh should be marked as failed")
+		})
+	}
+
+	apiServer(t, "/v2/droplets", `{"droplets": []}`, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceDroplets)
+		dos := NewDigitalOceanService(dob)
+		assert.Empty(t, dos.RefreshErrors(), "there should be no errors")
+		assert.True(t, dos.RefreshStatus()["droplets"].success, "refresh should be marked as successful")
+	})
+}
+
+func TestRetainDataOnError(t *testing.T) {
+	dob := getDOBuffer()
+	dos := NewDigitalOceanService(dob)
+	expected := map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc3"}: 1}
+
+	apiServer(t, "/v2/droplets", `{"droplets": [
+        {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}}]}`, func() {
+		dob.client.BaseURL = GodoBase
+		dob.update(context.Background(), resourceDroplets)
+	})
+
+	apiServerWithStatus(t, "/v2/droplets", 500, `{"id": "server_error", "message": "error"}`, func() {
+		dob.client.BaseURL = GodoBase
+		dob.update(context.Background(), resourceDroplets)
+	})
+
+	assert.Equal(t, expected, dos.Droplets(), "they should be equal")
+	assert.True(t, dos.RefreshStatus()["droplets"].stale(), "data should be marked as stale")
+
+	dob.snapshot.maxStaleness = time.Minute
+	assert.Equal(t, expected, dos.Droplets(), "they should be equal")
+
+	status := dob.snapshot.refreshStatus["droplets"]
+	status.lastSuccess = time.Now().Add(-2 * time.Minute)
+	dob.snapshot.refreshStatus["droplets"] = status
+	assert.Nil(t, dos.Droplets(), "expired data should not be served")
+}
+
+func TestSnapshotSwap(t *testing.T) {
+	apiServer(t, "/v2/droplets", `{"droplets": [
+        {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}}]}`, func() {
+		dob := getDOBuffer()
+		dos := NewDigitalOceanService(dob)
+		before := dos.Snapshot()
+
+		var refreshID uuid.UUID
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 5; i++ {
+				refreshID, _ = uuid.NewV4()
+				dob.update(withRefreshInfo(context.Background(), &refreshInfo{id: refreshID}), resourceDroplets)
+			}
+		}()
+
+		for i := 0; i < 5; i++ {
+			s := dos.Snapshot()
+			if _, ok := s.RefreshStatus()["droplets"]; ok {
+				assert.Len(t, s.Droplets(), 1, "snapshot should be consistent with its refresh status")
+			}
+		}
+		<-done
+
+		after := dos.Snapshot()
+		assert.Empty(t, before.Droplets(), "earlier snapshots should not be modified")
+		assert.Len(t, after.Droplets(), 1, "latest snapshot should be swapped in")
+		assert.Equal(t, refreshID, after.RefreshID(), "latest snapshot should belong to the latest refresh")
+	})
+}
+
+func TestIndependentRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/droplets":
+			fmt.Fprintln(w, `{"droplets": [{"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}}]}`)
+		case "/v2/volumes":
+			fmt.Fprintln(w, `{"volumes": [{"droplet_ids":[], "size_gigabytes":100, "region":{"slug":"nyc3"}}]}`)
+		default:
+			t.Errorf("Wrong URL: %v", r.URL.String())
+		}
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		panic(err)
+	}
+	GodoBase = u
+
+	dob := getDOBuffer()
+	dos := NewDigitalOceanService(dob)
+
+	var wg sync.WaitGroup
+	for _, resource := range []string{resourceDroplets, resourceVolumes} {
+		wg.Add(1)
+		go func(resource string) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				dob.update(context.Background(), resource)
+			}
+		}(resource)
+	}
+	wg.Wait()
+
+	assert.Len(t, dos.Droplets(), 1, "droplets should be merged into the snapshot")
+	assert.Len(t, dos.Volumes(), 1, "volumes should be merged into the snapshot")
+	assert.Len(t, dos.RefreshStatus(), 2, "both resource types should have a refresh status")
+}
+
+func TestGroupResources(t *testing.T) {
+	dob := getDOBuffer()
+	dob.refreshInterval = time.Minute
+	dob.resourceInterval = map[string]time.Duration{
+		resourceTags:    10 * time.Minute,
+		resourceVolumes: 10 * time.Minute,
+	}
+
+	expected := [][]string{
+		[]string{resourceAccount, resourceCertificates, resourceDroplets, resourceFirewalls, resourceFloatingIPs, resourceKubernetes, resourceLoadBalancers, resourceSnapshots},
+		[]string{resourceApps, resourceDatabases},
+		[]string{resourceBilling, resourceInvoiceItems},
+		[]string{resourceDomains, resourceRegistry, resourceTags, resourceVolumes},
+	}
+	assert.Equal(t, expected, dob.groupResources(Resources()), "they should be equal")
+	assert.Equal(t, 5*time.Minute, dob.intervalFor(expected[1]), "they should be equal")
+	assert.Equal(t, time.Hour, dob.intervalFor(expected[2]), "they should be equal")
+	assert.Equal(t, 10*time.Minute, dob.intervalFor(expected[3]), "they should be equal")
+}
+
+func TestPaginatedRefresh(t *testing.T) {
+	var pageTests = []struct {
+		meta     string
+		expected map[DropletCounter]int
+	}{
+		// The total is known, so pages 2 and 3 are requested concurrently.
+		{`{"total": 401}`,
+			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc1"}: 1,
+				DropletCounter{status: "active", size: "1gb", region: "nyc2"}: 1,
+				DropletCounter{status: "active", size: "1gb", region: "nyc3"}: 1}},
+		// The total is unknown, so the links are followed page by page.
+		{`null`,
+			map[DropletCounter]int{DropletCounter{status: "active", size: "1gb", region: "nyc1"}: 1,
+				DropletCounter{status: "active", size: "1gb", region: "nyc2"}: 1,
+				DropletCounter{status: "active", size: "1gb", region: "nyc3"}: 1}},
+	}
+
+	for _, tt := range pageTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page := r.URL.Query().Get("page")
+			links := `{"pages": {"last": "http://example.com/v2/droplets?page=3&per_page=200", "next": "http://example.com/v2/droplets?page=2&per_page=200"}}`
+			switch page {
+			case "2":
+				links = `{"pages": {"last": "http://example.com/v2/droplets?page=3&per_page=200", "prev": "http://example.com/v2/droplets?page=1&per_page=200", "next": "http://example.com/v2/droplets?page=3&per_page=200"}}`
+			case "3":
+				links = `{"pages": {"prev": "http://example.com/v2/droplets?page=2&per_page=200"}}`
+			}
+
+			fmt.Fprintf(w, `{"droplets": [{"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc%s"}}], "links": %s, "meta": %s}`, page, links, tt.meta)
+		}))
+
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			panic(err)
+		}
+		GodoBase = u
+
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceDroplets)
+		dos := NewDigitalOceanService(dob)
+		assert.Equal(t, tt.expected, dos.Droplets(), "they should be equal")
+
+		server.Close()
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintln(w, `{"droplets": []}`)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		panic(err)
+	}
+	GodoBase = u
+
+	dob := getDOBuffer()
+	dob.requestTimeout = 10 * time.Millisecond
+	dob.update(context.Background(), resourceDroplets)
+	dos := NewDigitalOceanService(dob)
+
+	assert.Equal(t, map[RefreshErrorCounter]int{RefreshErrorCounter{resource: "droplets", class: "timeout"}: 1}, dos.RefreshErrors(), "they should be equal")
+	assert.Equal(t, map[string]int{"droplets": 1}, dos.RefreshTimeouts(), "they should be equal")
+}
+
+func TestRetry(t *testing.T) {
+	var retryTests = []struct {
+		status int
+		header http.Header
+	}{
+		{503, http.Header{}},
+		{429, http.Header{"Retry-After": []string{"0"}}},
+	}
+
+	for _, tt := range retryTests {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("RateLimit-Limit", "5000")
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(5000-requests))
+			w.Header().Set("RateLimit-Reset", "1500000000")
+			if requests == 1 {
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprintln(w, `{"id": "error", "message": "error"}`)
+				return
+			}
+			fmt.Fprintln(w, `{"droplets": [{"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}}]}`)
+		}))
+
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			panic(err)
+		}
+		GodoBase = u
+
+		dob := getDOBuffer()
+		dob.maxRetries = 2
+		dob.update(context.Background(), resourceDroplets)
+		dos := NewDigitalOceanService(dob)
+
+		assert.Equal(t, 2, requests, "the failed request should be retried once")
+		assert.Equal(t, 2, dos.RefreshStatus()["droplets"].requests, "retries should be counted as requests")
+		assert.Len(t, dos.Droplets(), 1, "the retried request should be used")
+		assert.Empty(t, dos.RefreshErrors(), "there should be no errors")
+		assert.Equal(t, 5000, dos.Rate().Limit, "they should be equal")
+		assert.Equal(t, 4998, dos.Rate().Remaining, "they should be equal")
+
+		server.Close()
+	}
+}
+
+func TestAdaptiveRefreshInterval(t *testing.T) {
+	var intervalTests = []struct {
+		adaptive bool
+		requests int
+		rate     godo.Rate
+		expected time.Duration
+	}{
+		// Adaptive mode disabled.
+		{false, 1000, godo.Rate{Limit: 5000}, time.Minute},
+		// Rate limit not yet known.
+		{true, 1000, godo.Rate{}, time.Minute},
+		// 5 requests every minute is well within half of 5000 per hour.
+		{true, 5, godo.Rate{Limit: 5000}, time.Minute},
+		// 250 requests may only be made 10 times per hour within the budget.
+		{true, 250, godo.Rate{Limit: 5000}, 6 * time.Minute},
+	}
+
+	for _, tt := range intervalTests {
+		dob := getDOBuffer()
+		dob.adaptive = tt.adaptive
+		dob.rateLimitBudget = 0.5
+		assert.Equal(t, tt.expected, dob.nextInterval(resourceDroplets, time.Minute, tt.requests, tt.rate), "they should be equal")
+	}
+}
+
+func TestAdaptiveRefreshIntervalGroups(t *testing.T) {
+	dob := getDOBuffer()
+	dob.adaptive = true
+	dob.rateLimitBudget = 0.5
+	rate := godo.Rate{Limit: 5000}
+
+	// On its own the hourly group stays well within the budget.
+	assert.Equal(t, time.Hour, dob.nextInterval(resourceBilling, time.Hour, 600, rate), "they should be equal")
+
+	// Together the groups would make 14400 + 600 requests per hour, six
+	// times the budget of 2500, so both are stretched six times rather than
+	// the expensive group getting only half of the budget.
+	assert.Equal(t, 6*time.Minute, dob.nextInterval(resourceDroplets, time.Minute, 240, rate), "they should be equal")
+	assert.Equal(t, 6*time.Hour, dob.nextInterval(resourceBilling, time.Hour, 600, rate), "they should be equal")
+}
+
+func TestAccount(t *testing.T) {
+	resps := map[string]string{
+		"/v2/account": `{"account": {"droplet_limit": 10, "floating_ip_limit": 3, "volume_limit": 100,
+            "email_verified": true, "status": "active"}}`,
+		"/v2/droplets": `{"droplets": [
+            {"status":"active", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}},
+            {"status":"off", "size":{"slug":"1gb"}, "region":{"slug":"nyc3"}}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceAccount, resourceDroplets)
+
+		account := dob.Snapshot().Account()
+		if assert.NotNil(t, account) {
+			assert.Equal(t, 10, account.DropletLimit, "they should be equal")
+			assert.Equal(t, "active", account.Status, "they should be equal")
+		}
+
+		// Utilization is only reported for resource types which have been
+		// refreshed.
+		utilization := collectValues(t, NewAccountCollector(dob), "digitalocean_account_limit_utilization_ratio")
+		assert.Equal(t, map[string]float64{"droplets": 0.2}, utilization, "they should be equal")
+	})
+}
+
+func TestBilling(t *testing.T) {
+	resps := map[string]string{
+		"/v2/customers/my/balance": `{"month_to_date_balance": "23.44", "account_balance": "12.23",
+            "month_to_date_usage": "11.21", "generated_at": "2019-07-09T15:01:12Z"}`,
+		"/v2/customers/my/invoices": `{"invoices": [
+            {"invoice_uuid": "22737513-0ea7-4206-8ceb-98a575af7681", "amount": "12.34", "invoice_period": "2019-12"},
+            {"invoice_uuid": "fdabb512-6faf-443c-ba2e-665452332a9e", "amount": "23.45", "invoice_period": "2019-11"}],
+            "invoice_preview": {"invoice_uuid": "1afe95e6-0958-4eb0-8d9a-9c5060d3ef03", "amount": "34.56", "invoice_period": "2020-01"}}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceBilling)
+
+		var billingTests = []struct {
+			name     string
+			expected map[string]float64
+		}{
+			{"digitalocean_billing_month_to_date_usage_dollars", map[string]float64{"": 11.21}},
+			{"digitalocean_billing_account_balance_dollars", map[string]float64{"": 12.23}},
+			{"digitalocean_billing_month_to_date_balance_dollars", map[string]float64{"": 23.44}},
+			{"digitalocean_billing_generated_timestamp_seconds", map[string]float64{"": 1562684472}},
+			{"digitalocean_billing_invoice_amount_dollars", map[string]float64{
+				"2019-12,22737513-0ea7-4206-8ceb-98a575af7681": 12.34,
+				"2019-11,fdabb512-6faf-443c-ba2e-665452332a9e": 23.45,
+			}},
+		}
+
+		for _, tt := range billingTests {
+			assert.Equal(t, tt.expected, collectValues(t, NewBillingCollector(dob), tt.name), "they should be equal")
+		}
+	})
+}
+
+func TestInvoiceItems(t *testing.T) {
+	resps := map[string]string{
+		"/v2/customers/my/invoices": `{"invoices": [
+            {"invoice_uuid": "22737513-0ea7-4206-8ceb-98a575af7681", "amount": "12.34", "invoice_period": "2019-12"}],
+            "invoice_preview": {"invoice_uuid": "1afe95e6-0958-4eb0-8d9a-9c5060d3ef03", "amount": "34.56", "invoice_period": "2020-01"}}`,
+		"/v2/customers/my/invoices/22737513-0ea7-4206-8ceb-98a575af7681": `{"invoice_items": [
+            {"product": "Droplets", "group_description": "web", "amount": "10.00", "project_name": "website", "category": "iaas"},
+            {"product": "Droplets", "group_description": "web", "amount": "2.00", "project_name": "website", "category": "iaas"},
+            {"product": "Spaces", "amount": "0.34", "project_name": "assets", "category": "iaas"}]}`,
+		"/v2/customers/my/invoices/1afe95e6-0958-4eb0-8d9a-9c5060d3ef03": `{"invoice_items": [
+            {"product": "Droplets", "group_description": "web", "amount": "5.00", "project_name": "website", "category": "iaas"}]}`,
+	}
+
+	apiServerWithPaths(t, resps, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceInvoiceItems)
+
+		expected := map[InvoiceItemCounter]float64{
+			InvoiceItemCounter{invoice: "closed", invoicePeriod: "2019-12", product: "Droplets", project: "website", groupDescription: "web", category: "iaas"}:  12.00,
+			InvoiceItemCounter{invoice: "closed", invoicePeriod: "2019-12", product: "Spaces", project: "assets", category: "iaas"}:                              0.34,
+			InvoiceItemCounter{invoice: "preview", invoicePeriod: "2020-01", product: "Droplets", project: "website", groupDescription: "web", category: "iaas"}: 5.00,
+		}
+		assert.Equal(t, expected, dob.Snapshot().InvoiceItems(), "they should be equal")
+	})
+}
+
+func TestCollectorResources(t *testing.T) {
+	var resourceTests = []struct {
+		collectors []string
+		expected   []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"volumes", "droplets"}, []string{resourceDroplets, resourceVolumes}},
+		{[]string{"account", "apps", "billing", "certificates", "databases", "domains", "droplets", "firewalls", "floating_ips", "invoice_items", "kubernetes", "load_balancers", "registry", "snapshots", "tags", "volumes"}, Resources()},
+	}
+
+	for _, tt := range resourceTests {
+		resources, err := CollectorResources(tt.collectors)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, resources, "they should be equal")
+	}
+
+	_, err := CollectorResources([]string{"unknown"})
+	assert.Error(t, err)
+}
+
+func TestExporterDescribe(t *testing.T) {
+	resp := `{"droplets": [
+        {"status":"active", "size":{"slug":"1gb", "price_hourly": 0.014880, "price_monthly": 5.0}, "region":{"slug":"nyc3"}}]}`
+
+	apiServer(t, "/v2/droplets", resp, func() {
+		dob := getDOBuffer()
+		dob.update(context.Background(), resourceDroplets)
+
+		var collectors []string
+		for name := range Collectors() {
+			collectors = append(collectors, name)
+		}
+
+		exporter, err := New(NewDigitalOceanService(dob), collectors)
+		assert.NoError(t, err)
+
+		// A pedantic registry fails to gather any metric whose descriptor
+		// was not sent by Describe.
+		registry := prometheus.NewPedanticRegistry()
+		assert.NoError(t, registry.Register(exporter))
+		_, err = registry.Gather()
+		assert.NoError(t, err)
+	})
+
+	_, err := New(NewDigitalOceanService(getDOBuffer()), []string{"unknown"})
+	assert.Error(t, err)
+}
+
 var GodoBase *url.URL
 
 type TokenSource struct {
@@ -196,19 +1224,73 @@ func getDOBuffer() *DigitalOceanBuffer {
 	c.BaseURL = GodoBase
 
 	dob := &DigitalOceanBuffer{
-		client: c,
+		client:         c,
+		requests:       make(chan struct{}, DefaultConcurrency),
+		retryBaseDelay: time.Millisecond,
+		retryMaxDelay:  10 * time.Millisecond,
+		snapshot:       newSnapshot(),
 	}
 
 	return dob
 }
 
+// collectValues collects the metrics of the named family from c, keyed by
+// their comma-separated label values ordered by label name.
+func collectValues(t testing.TB, c prometheus.Collector, name string) map[string]float64 {
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetValue())
+			}
+			values[strings.Join(labels, ",")] = m.GetGauge().GetValue()
+		}
+	}
+
+	return values
+}
+
 func apiServer(t testing.TB, path string, resp string, test func()) {
+	apiServerWithStatus(t, path, 200, resp, test)
+}
+
+func apiServerWithStatus(t testing.TB, path string, status int, resp string, test func()) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != path {
 			t.Errorf("Wrong URL: %v", r.URL.String())
 			return
 		}
-		w.WriteHeader(200)
+		w.WriteHeader(status)
+		fmt.Fprintln(w, resp)
+	}))
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		panic(err)
+	}
+	GodoBase = u
+
+	defer server.Close()
+	test()
+}
+
+func apiServerWithPaths(t testing.TB, resps map[string]string, test func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := resps[r.URL.Path]
+		if !ok {
+			t.Errorf("Wrong URL: %v", r.URL.String())
+			return
+		}
 		fmt.Fprintln(w, resp)
 	}))
 
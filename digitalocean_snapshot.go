@@ -0,0 +1,437 @@
+package digitaloceanexporter
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/satori/go.uuid"
+)
+
+// A Snapshot is an immutable view of the resources in a DigitalOcean account.
+// A DigitalOceanBuffer builds each Snapshot in full, merging the latest data
+// of each resource type, and then swaps it in atomically, so a scrape always
+// sees a consistent view. Neither a Snapshot nor the maps it returns may be
+// modified once it has been swapped in.
+type Snapshot struct {
+	refreshID    uuid.UUID
+	maxStaleness time.Duration
+	dropletTags  TagConfig
+
+	account          *godo.Account
+	appList          []App
+	billing          *Billing
+	certificateList  []godo.Certificate
+	databaseList     []DatabaseCluster
+	domainRecords    map[DomainRecordCounter]int
+	domainMinTTLs    map[string]int
+	addressRecords   []AddressRecord
+	droplets         map[DropletCounter]int
+	dropletList      []godo.Droplet
+	firewallList     []godo.Firewall
+	floatingIPs      map[FlipCounter]int
+	floatingIPList   []godo.FloatingIP
+	invoiceItems     map[InvoiceItemCounter]float64
+	kubernetesList   []KubernetesCluster
+	loadBalancers    map[LoadBalancerCounter]int
+	loadBalancerList []godo.LoadBalancer
+	registry         *Registry
+	snapshots        map[SnapshotCounter]int
+	tags             map[TagCounter]int
+	volumes          map[VolumeCounter]int
+
+	dropletFeatures   map[DropletFeatureCounter]int
+	kubernetesWorkers map[int]bool
+	snapshotSizes     map[SnapshotCounter]float64
+	oldestSnapshots   map[SnapshotSourceCounter]time.Time
+
+	dropletCosts      map[DropletCostCounter]Cost
+	floatingIPCosts   map[string]Cost
+	loadBalancerCosts map[string]Cost
+	snapshotCosts     map[SnapshotCounter]Cost
+	volumeCosts       map[string]Cost
+
+	queryDuration time.Duration
+	rate          godo.Rate
+
+	refreshStatus   map[string]RefreshStatus
+	refreshErrors   map[RefreshErrorCounter]int
+	refreshTimeouts map[string]int
+}
+
+func newSnapshot() *Snapshot {
+	return &Snapshot{
+		refreshStatus:   make(map[string]RefreshStatus),
+		refreshErrors:   make(map[RefreshErrorCounter]int),
+		refreshTimeouts: make(map[string]int),
+	}
+}
+
+// next creates a copy of the Snapshot to be filled in by the refresh with the
+// given ID. Resource data is carried over so that it is retained for resource
+// types whose refresh fails.
+func (s *Snapshot) next(refreshID uuid.UUID) *Snapshot {
+	n := *s
+	n.refreshID = refreshID
+	n.queryDuration = 0
+
+	n.refreshStatus = make(map[string]RefreshStatus, len(s.refreshStatus))
+	for resource, status := range s.refreshStatus {
+		n.refreshStatus[resource] = status
+	}
+
+	n.refreshErrors = make(map[RefreshErrorCounter]int, len(s.refreshErrors))
+	for e, count := range s.refreshErrors {
+		n.refreshErrors[e] = count
+	}
+
+	n.refreshTimeouts = make(map[string]int, len(s.refreshTimeouts))
+	for resource, count := range s.refreshTimeouts {
+		n.refreshTimeouts[resource] = count
+	}
+
+	return &n
+}
+
+// countDroplets counts the Droplets of the Snapshot. The count is derived
+// from both the Droplets and the DOKS clusters, so it is recounted whenever
+// either is refreshed. Whether a Droplet is a DOKS worker is left empty until
+// the DOKS clusters have been refreshed.
+func (s *Snapshot) countDroplets() {
+	counters := make(map[DropletCounter]int)
+
+	for _, d := range s.dropletList {
+		var doksWorker string
+		if s.kubernetesWorkers != nil {
+			doksWorker = strconv.FormatBool(s.kubernetesWorkers[d.ID])
+		}
+
+		tags, promoted := s.dropletTags.labels(d.Tags)
+		c := DropletCounter{
+			d.Status,
+			d.Region.Slug,
+			d.Size.Slug,
+			doksWorker,
+			tags,
+			promoted,
+		}
+		counters[c]++
+	}
+
+	s.droplets = counters
+}
+
+// RefreshID reports the ID of the most recent refresh merged into the
+// Snapshot.
+func (s *Snapshot) RefreshID() uuid.UUID {
+	return s.refreshID
+}
+
+// Account retrieves the DigitalOcean account, or nil if it is not known.
+func (s *Snapshot) Account() *godo.Account {
+	if s.expired(resourceAccount) {
+		return nil
+	}
+	return s.account
+}
+
+// AppList retrieves every App Platform app together with its most recent
+// deployments.
+func (s *Snapshot) AppList() []App {
+	if s.expired(resourceApps) {
+		return nil
+	}
+	return s.appList
+}
+
+// Billing retrieves the balance and recent invoices of the account, or nil if
+// they are not known.
+func (s *Snapshot) Billing() *Billing {
+	if s.expired(resourceBilling) {
+		return nil
+	}
+	return s.billing
+}
+
+// CertificateList retrieves every certificate as returned by the DigitalOcean
+// API.
+func (s *Snapshot) CertificateList() []godo.Certificate {
+	if s.expired(resourceCertificates) {
+		return nil
+	}
+	return s.certificateList
+}
+
+// DatabaseList retrieves every database cluster together with its read
+// replicas, connection pools and trusted sources.
+func (s *Snapshot) DatabaseList() []DatabaseCluster {
+	if s.expired(resourceDatabases) {
+		return nil
+	}
+	return s.databaseList
+}
+
+// DomainRecords retrieves a count of DNS records grouped by domain and record
+// type.
+func (s *Snapshot) DomainRecords() map[DomainRecordCounter]int {
+	if s.expired(resourceDomains) {
+		return nil
+	}
+	return s.domainRecords
+}
+
+// DomainMinTTLs retrieves the lowest TTL of the records of each domain in
+// seconds, keyed by domain.
+func (s *Snapshot) DomainMinTTLs() map[string]int {
+	if s.expired(resourceDomains) {
+		return nil
+	}
+	return s.domainMinTTLs
+}
+
+// AddressRecords retrieves every A and AAAA record of every domain.
+func (s *Snapshot) AddressRecords() []AddressRecord {
+	if s.expired(resourceDomains) {
+		return nil
+	}
+	return s.addressRecords
+}
+
+// Droplets retrieves a count of Droplets grouped by status, size, and region.
+func (s *Snapshot) Droplets() map[DropletCounter]int {
+	if s.expired(resourceDroplets) {
+		return nil
+	}
+	return s.droplets
+}
+
+// DropletList retrieves every Droplet as returned by the DigitalOcean API.
+func (s *Snapshot) DropletList() []godo.Droplet {
+	if s.expired(resourceDroplets) {
+		return nil
+	}
+	return s.dropletList
+}
+
+// DropletFeatures retrieves a count of Droplets with and without each feature
+// grouped by region and tags.
+func (s *Snapshot) DropletFeatures() map[DropletFeatureCounter]int {
+	if s.expired(resourceDroplets) {
+		return nil
+	}
+	return s.dropletFeatures
+}
+
+// DropletTags retrieves the configuration used to turn the tags of Droplets
+// into labels.
+func (s *Snapshot) DropletTags() TagConfig {
+	return s.dropletTags
+}
+
+// FirewallList retrieves every Cloud Firewall as returned by the DigitalOcean
+// API.
+func (s *Snapshot) FirewallList() []godo.Firewall {
+	if s.expired(resourceFirewalls) {
+		return nil
+	}
+	return s.firewallList
+}
+
+// FloatingIPs retrieves a count of Floating IPs grouped by status and region.
+func (s *Snapshot) FloatingIPs() map[FlipCounter]int {
+	if s.expired(resourceFloatingIPs) {
+		return nil
+	}
+	return s.floatingIPs
+}
+
+// FloatingIPList retrieves every Floating IP as returned by the DigitalOcean
+// API.
+func (s *Snapshot) FloatingIPList() []godo.FloatingIP {
+	if s.expired(resourceFloatingIPs) {
+		return nil
+	}
+	return s.floatingIPList
+}
+
+// InvoiceItems retrieves the amount of the items of the most recent closed
+// invoice and of the invoice preview grouped by invoice, product, project,
+// group description, and category.
+func (s *Snapshot) InvoiceItems() map[InvoiceItemCounter]float64 {
+	if s.expired(resourceInvoiceItems) {
+		return nil
+	}
+	return s.invoiceItems
+}
+
+// KubernetesList retrieves every DOKS cluster together with the versions it
+// can be upgraded to.
+func (s *Snapshot) KubernetesList() []KubernetesCluster {
+	if s.expired(resourceKubernetes) {
+		return nil
+	}
+	return s.kubernetesList
+}
+
+// LoadBalancers retrieves a count of Load Balancers grouped by status and region.
+func (s *Snapshot) LoadBalancers() map[LoadBalancerCounter]int {
+	if s.expired(resourceLoadBalancers) {
+		return nil
+	}
+	return s.loadBalancers
+}
+
+// LoadBalancerList retrieves every Load Balancer as returned by the
+// DigitalOcean API.
+func (s *Snapshot) LoadBalancerList() []godo.LoadBalancer {
+	if s.expired(resourceLoadBalancers) {
+		return nil
+	}
+	return s.loadBalancerList
+}
+
+// Registry retrieves the container registry of the account, or nil if it is
+// not known or the account has none.
+func (s *Snapshot) Registry() *Registry {
+	if s.expired(resourceRegistry) {
+		return nil
+	}
+	return s.registry
+}
+
+// Snapshots retrieves a count of Snapshots and custom images grouped by
+// region and resource type.
+func (s *Snapshot) Snapshots() map[SnapshotCounter]int {
+	if s.expired(resourceSnapshots) {
+		return nil
+	}
+	return s.snapshots
+}
+
+// SnapshotSizes retrieves the total size in GB of Snapshots and custom images
+// grouped by region and resource type.
+func (s *Snapshot) SnapshotSizes() map[SnapshotCounter]float64 {
+	if s.expired(resourceSnapshots) {
+		return nil
+	}
+	return s.snapshotSizes
+}
+
+// OldestSnapshots retrieves the time the oldest Snapshot of each resource was
+// created.
+func (s *Snapshot) OldestSnapshots() map[SnapshotSourceCounter]time.Time {
+	if s.expired(resourceSnapshots) {
+		return nil
+	}
+	return s.oldestSnapshots
+}
+
+// Tags retrieves a count of Tags grouped by name and resource type.
+func (s *Snapshot) Tags() map[TagCounter]int {
+	if s.expired(resourceTags) {
+		return nil
+	}
+	return s.tags
+}
+
+// Volumes retrieves a count of Volumes grouped by status, size, and region.
+func (s *Snapshot) Volumes() map[VolumeCounter]int {
+	if s.expired(resourceVolumes) {
+		return nil
+	}
+	return s.volumes
+}
+
+// DropletCosts retrieves the cost of Droplets grouped by region, size, and
+// tags.
+func (s *Snapshot) DropletCosts() map[DropletCostCounter]Cost {
+	if s.expired(resourceDroplets) {
+		return nil
+	}
+	return s.dropletCosts
+}
+
+// FloatingIPCosts retrieves the cost of Floating IPs grouped by region.
+func (s *Snapshot) FloatingIPCosts() map[string]Cost {
+	if s.expired(resourceFloatingIPs) {
+		return nil
+	}
+	return s.floatingIPCosts
+}
+
+// LoadBalancerCosts retrieves the cost of Load Balancers grouped by region.
+func (s *Snapshot) LoadBalancerCosts() map[string]Cost {
+	if s.expired(resourceLoadBalancers) {
+		return nil
+	}
+	return s.loadBalancerCosts
+}
+
+// SnapshotCosts retrieves the cost of Snapshots grouped by region and
+// resource type.
+func (s *Snapshot) SnapshotCosts() map[SnapshotCounter]Cost {
+	if s.expired(resourceSnapshots) {
+		return nil
+	}
+	return s.snapshotCosts
+}
+
+// VolumeCosts retrieves the cost of Volumes grouped by region.
+func (s *Snapshot) VolumeCosts() map[string]Cost {
+	if s.expired(resourceVolumes) {
+		return nil
+	}
+	return s.volumeCosts
+}
+
+// QueryDuration reports the time elapsed while querying the DigitalOcean API.
+func (s *Snapshot) QueryDuration() time.Duration {
+	return s.queryDuration
+}
+
+// Rate retrieves the DigitalOcean API rate limit observed by the most recent
+// request before the Snapshot was swapped in.
+func (s *Snapshot) Rate() godo.Rate {
+	return s.rate
+}
+
+// RefreshStatus retrieves the outcome of the most recent refresh of each
+// resource type.
+func (s *Snapshot) RefreshStatus() map[string]RefreshStatus {
+	return s.refreshStatus
+}
+
+// RefreshErrors retrieves a count of failed refreshes grouped by resource type
+// and error class.
+func (s *Snapshot) RefreshErrors() map[RefreshErrorCounter]int {
+	return s.refreshErrors
+}
+
+// RefreshTimeouts retrieves a count of refreshes aborted by a timeout grouped
+// by resource type.
+func (s *Snapshot) RefreshTimeouts() map[string]int {
+	return s.refreshTimeouts
+}
+
+// available reports whether data for a resource type has been refreshed
+// successfully and may still be served.
+func (s *Snapshot) available(resource string) bool {
+	return !s.refreshStatus[resource].lastSuccess.IsZero() && !s.expired(resource)
+}
+
+// expired reports whether the data retained for a resource type after a
+// failed refresh is older than the configured maximum staleness and should no
+// longer be served. Data from a successful refresh never expires, however
+// long the resource type's refresh interval.
+func (s *Snapshot) expired(resource string) bool {
+	if s.maxStaleness == 0 {
+		return false
+	}
+
+	status, ok := s.refreshStatus[resource]
+	if !ok || !status.stale() {
+		return false
+	}
+
+	return time.Since(status.lastSuccess) > s.maxStaleness
+}
@@ -0,0 +1,137 @@
+package digitaloceanexporter
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceSnapshots, true, []string{resourceSnapshots}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewSnapshotCollector(dos)
+	})
+}
+
+// A SnapshotCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Droplet and Volume Snapshots and custom images.
+type SnapshotCollector struct {
+	Snapshots   *prometheus.Desc
+	Size        *prometheus.Desc
+	OldestAge   *prometheus.Desc
+	CostHourly  *prometheus.Desc
+	CostMonthly *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that SnapshotCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &SnapshotCollector{}
+
+// NewSnapshotCollector creates a new SnapshotCollector which collects metrics
+// about the Snapshots and custom images in a DigitalOcean account.
+func NewSnapshotCollector(dos DigitalOceanSource) *SnapshotCollector {
+	return &SnapshotCollector{
+		Snapshots: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshots", "count"),
+			"Number of Snapshots and custom images by region and resource type.",
+			[]string{"region", "resource_type"},
+			nil,
+		),
+		Size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshots", "size_gigabytes"),
+			"Total size of Snapshots and custom images by region and resource type in GB.",
+			[]string{"region", "resource_type"},
+			nil,
+		),
+		OldestAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshots", "oldest_age_seconds"),
+			"Age of the oldest Snapshot of each Droplet or Volume in seconds.",
+			[]string{"resource_id", "resource_type"},
+			nil,
+		),
+		CostHourly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshots", "cost_hourly_dollars"),
+			"Estimated hourly cost of Snapshots and custom images by region and resource type in US dollars.",
+			[]string{"region", "resource_type"},
+			nil,
+		),
+		CostMonthly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "snapshots", "cost_monthly_dollars"),
+			"Estimated monthly cost of Snapshots and custom images by region and resource type in US dollars.",
+			[]string{"region", "resource_type"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *SnapshotCollector) Describe(ch chan<- *prometheus.Desc) {
+	ds := []*prometheus.Desc{
+		c.Snapshots,
+		c.Size,
+		c.OldestAge,
+		c.CostHourly,
+		c.CostMonthly,
+	}
+
+	for _, d := range ds {
+		ch <- d
+	}
+}
+
+// Collect sends the metric values for each metric pertaining to Snapshots and
+// custom images to the provided prometheus Metric channel.
+func (c *SnapshotCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	for sn, count := range s.Snapshots() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Snapshots,
+			prometheus.GaugeValue,
+			float64(count),
+			sn.region,
+			sn.resourceType,
+		)
+	}
+
+	for sn, size := range s.SnapshotSizes() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Size,
+			prometheus.GaugeValue,
+			size,
+			sn.region,
+			sn.resourceType,
+		)
+	}
+
+	now := time.Now()
+	for source, created := range s.OldestSnapshots() {
+		ch <- prometheus.MustNewConstMetric(
+			c.OldestAge,
+			prometheus.GaugeValue,
+			now.Sub(created).Seconds(),
+			source.resourceID,
+			source.resourceType,
+		)
+	}
+
+	for sn, cost := range s.SnapshotCosts() {
+		ch <- prometheus.MustNewConstMetric(
+			c.CostHourly,
+			prometheus.GaugeValue,
+			cost.hourly,
+			sn.region,
+			sn.resourceType,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.CostMonthly,
+			prometheus.GaugeValue,
+			cost.monthly,
+			sn.region,
+			sn.resourceType,
+		)
+	}
+}
@@ -0,0 +1,57 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceTags, true, []string{resourceTags}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewTagCollector(dos)
+	})
+}
+
+// A TagCollector is a Prometheus collector for metrics regarding DigitalOcean
+// Tags.
+type TagCollector struct {
+	Tags *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that TagCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &TagCollector{}
+
+// NewTagCollector creates a new TagCollector which collects metrics about the
+// Tags in a DigitalOcean account.
+func NewTagCollector(dos DigitalOceanSource) *TagCollector {
+	return &TagCollector{
+		Tags: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tags", "count"),
+			"Count of tagged resources by name and resource type.",
+			[]string{"name", "resource_type"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *TagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.Tags
+}
+
+// Collect sends the metric values for each metric pertaining to Tags to the
+// provided prometheus Metric channel.
+func (c *TagCollector) Collect(ch chan<- prometheus.Metric) {
+	for t, count := range c.dos.Snapshot().Tags() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Tags,
+			prometheus.GaugeValue,
+			float64(count),
+			t.name,
+			t.resourceType,
+		)
+	}
+}
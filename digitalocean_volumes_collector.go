@@ -0,0 +1,91 @@
+package digitaloceanexporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerCollector(resourceVolumes, true, []string{resourceVolumes}, func(dos DigitalOceanSource) prometheus.Collector {
+		return NewVolumeCollector(dos)
+	})
+}
+
+// A VolumeCollector is a Prometheus collector for metrics regarding
+// DigitalOcean Block Storage Volumes.
+type VolumeCollector struct {
+	Volumes     *prometheus.Desc
+	CostHourly  *prometheus.Desc
+	CostMonthly *prometheus.Desc
+
+	dos DigitalOceanSource
+}
+
+// Verify that VolumeCollector implements the prometheus.Collector interface.
+var _ prometheus.Collector = &VolumeCollector{}
+
+// NewVolumeCollector creates a new VolumeCollector which collects metrics
+// about the Block Storage Volumes in a DigitalOcean account.
+func NewVolumeCollector(dos DigitalOceanSource) *VolumeCollector {
+	return &VolumeCollector{
+		Volumes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "volumes", "count"),
+			"Number of Volumes by region, size in GiB, and status.",
+			[]string{"region", "size", "status"},
+			nil,
+		),
+		CostHourly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "volumes", "cost_hourly_dollars"),
+			"Estimated hourly cost of Volumes by region in US dollars.",
+			[]string{"region"},
+			nil,
+		),
+		CostMonthly: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "volumes", "cost_monthly_dollars"),
+			"Estimated monthly cost of Volumes by region in US dollars.",
+			[]string{"region"},
+			nil,
+		),
+
+		dos: dos,
+	}
+}
+
+// Describe sends the descriptors of each metric over to the provided channel.
+// The corresponding metric values are sent separately.
+func (c *VolumeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.Volumes
+	ch <- c.CostHourly
+	ch <- c.CostMonthly
+}
+
+// Collect sends the metric values for each metric pertaining to Volumes to the
+// provided prometheus Metric channel.
+func (c *VolumeCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.dos.Snapshot()
+
+	for v, count := range s.Volumes() {
+		ch <- prometheus.MustNewConstMetric(
+			c.Volumes,
+			prometheus.GaugeValue,
+			float64(count),
+			v.region,
+			v.size,
+			v.status,
+		)
+	}
+
+	for region, cost := range s.VolumeCosts() {
+		ch <- prometheus.MustNewConstMetric(
+			c.CostHourly,
+			prometheus.GaugeValue,
+			cost.hourly,
+			region,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.CostMonthly,
+			prometheus.GaugeValue,
+			cost.monthly,
+			region,
+		)
+	}
+}
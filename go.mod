@@ -0,0 +1,35 @@
+module github.com/andrewsomething/digitalocean_exporter
+
+go 1.22
+
+require (
+	github.com/Sirupsen/logrus v1.0.5
+	github.com/digitalocean/godo v1.126.0
+	github.com/prometheus/client_golang v0.9.4
+	github.com/satori/go.uuid v1.2.1-0.20181028125025-b2ce2384e17b
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/oauth2 v0.23.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/protobuf v1.3.1 // indirect
+	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
+	github.com/hashicorp/go-retryablehttp v0.7.7 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_model v0.0.0-20190129233127-fd36f4220a90 // indirect
+	github.com/prometheus/common v0.4.1 // indirect
+	github.com/prometheus/procfs v0.0.2 // indirect
+	golang.org/x/crypto v0.9.0 // indirect
+	golang.org/x/sys v0.25.0 // indirect
+	golang.org/x/term v0.8.0 // indirect
+	golang.org/x/time v0.6.0 // indirect
+	gopkg.in/airbrake/gobrake.v2 v2.0.9 // indirect
+	gopkg.in/gemnasium/logrus-airbrake-hook.v2 v2.1.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/Sirupsen/logrus => github.com/sirupsen/logrus v1.0.5